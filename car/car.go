@@ -157,19 +157,17 @@ func UnpackCarFormat(input, output string) error {
 		return err
 	}
 
-	ds := sync.MutexWrap(datastore.NewMapDatastore())
-	bs := blockstore.NewBlockstore(ds)
-	bsvc := blockservice.New(bs, offline.Exchange(bs))
-	roots, err := carv1.LoadCar(context.Background(), bs, iFd)
+	bs := NewMemBlockstore()
+	ctx := context.Background()
+	roots, err := carv1.LoadCar(ctx, bs, iFd)
 	if err != nil {
 		log.Fatalf("Failed to load CAR file: %v", err)
 		return err
 	}
 
-	dagService := merkledag.NewDAGService(bsvc)
 	rootCid := roots.Roots[0]
 	outputDir := path.Join(output, rootCid.String())
-	err = restoreFilesFromDag(dagService, rootCid, outputDir)
+	err = RestoreDag(ctx, bs, rootCid, outputDir)
 	if err != nil {
 		log.Fatalf("Failed to restore files from DAG: %v", err)
 		return err
@@ -179,19 +177,32 @@ func UnpackCarFormat(input, output string) error {
 	return nil
 }
 
-func restoreFilesFromDag(dagService format.DAGService, rootCid cid.Cid, outputDir string) error {
+// NewMemBlockstore returns the in-memory blockstore CAR files are loaded into
+// before their files are restored.
+func NewMemBlockstore() blockstore.Blockstore {
+	return blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+}
+
+// RestoreDag writes the UnixFS file or directory root, whose blocks are all
+// in bs, to outputDir. Canceling ctx stops the extraction.
+func RestoreDag(ctx context.Context, bs blockstore.Blockstore, root cid.Cid, outputDir string) error {
+	bsvc := blockservice.New(bs, offline.Exchange(bs))
+	return restoreFilesFromDag(ctx, merkledag.NewDAGService(bsvc), root, outputDir)
+}
+
+func restoreFilesFromDag(ctx context.Context, dagService format.DAGService, rootCid cid.Cid, outputDir string) error {
 	cleaned := path.Clean(outputDir)
 	_, filename := path.Split(cleaned)
 
-	rootNode, err := dagService.Get(context.Background(), rootCid)
+	rootNode, err := dagService.Get(ctx, rootCid)
 	if err != nil {
-		log.Fatalf("Failed to get root node: %v", err)
+		log.Errorf("Failed to get root node: %v", err)
 		return fmt.Errorf("failed to get root node: %v", err)
 	}
 
-	file, err := unixfile.NewUnixfsFile(context.Background(), dagService, rootNode)
+	file, err := unixfile.NewUnixfsFile(ctx, dagService, rootNode)
 	if err != nil {
-		log.Fatalf("Failed to get unixfs file: %v", err)
+		log.Errorf("Failed to get unixfs file: %v", err)
 		return err
 	}
 
@@ -2,6 +2,7 @@ package car
 
 import (
 	"context"
+	"fmt"
 	"github.com/urchinfs/go-urchin2-sdk/utils"
 	"io"
 	"path/filepath"
@@ -68,8 +69,12 @@ func (di *DataImporter) Import(
 		path = v
 	case []byte:
 		target = files.NewBytesFile(v)
+	case files.Node:
+		target = v
 	case io.Reader:
 		target = files.NewReaderFile(v)
+	default:
+		return cid.Undef, fmt.Errorf("values of type %T cannot be imported", input)
 	}
 
 	adder, err := coreunix.NewAdder(ctx, nil, nil, di.dagServ)
@@ -0,0 +1,265 @@
+package car
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ipfs/boxo/ipld/merkledag"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	carv1 "github.com/ipld/go-car"
+	_ "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	_ "github.com/ipld/go-ipld-prime/codec/dagjson"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/multicodec"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	mh "github.com/multiformats/go-multihash"
+)
+
+var (
+	ErrHashMismatch  = errors.New("block content does not match its cid")
+	ErrMissingBlock  = errors.New("block missing from dag")
+	ErrRootMismatch  = errors.New("content does not match the requested root")
+	ErrUnknownCodec  = errors.New("cannot read links of block codec")
+	ErrUnrelatedRoot = errors.New("car file does not have the requested root")
+)
+
+// VerificationError reports content that does not match the root it was
+// requested for. Cid is the first bad block and Path its position under
+// Root, as far as it is known.
+type VerificationError struct {
+	Root cid.Cid
+	Cid  cid.Cid
+	Path string
+	Err  error
+}
+
+func (e *VerificationError) Error() string {
+	if !e.Cid.Defined() {
+		return fmt.Sprintf("verify %s: %v", e.Root, e.Err)
+	}
+	if e.Path == "" {
+		return fmt.Sprintf("verify %s: block %s: %v", e.Root, e.Cid, e.Err)
+	}
+	return fmt.Sprintf("verify %s: block %s at %s: %v", e.Root, e.Cid, e.Path, e.Err)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+type dagLink struct {
+	name string
+	cid  cid.Cid
+}
+
+// Verifier checks blocks received in any order against the DAG under root:
+// every block must hash to its CID, and once all are added every block
+// reachable from root must have been seen.
+type Verifier struct {
+	root  cid.Cid
+	links map[string][]dagLink
+	paths map[string]string
+}
+
+func NewVerifier(root cid.Cid) *Verifier {
+	return &Verifier{
+		root:  root,
+		links: make(map[string][]dagLink),
+		paths: map[string]string{root.KeyString(): root.String()},
+	}
+}
+
+// Add checks blk against its CID and records its links.
+func (v *Verifier) Add(blk blocks.Block) error {
+	c := blk.Cid()
+	sum, err := c.Prefix().Sum(blk.RawData())
+	if err != nil {
+		return v.errorAt(c, err)
+	}
+	if !bytes.Equal(sum.Hash(), c.Hash()) {
+		return v.errorAt(c, ErrHashMismatch)
+	}
+
+	links, err := blockLinks(c, blk.RawData())
+	if err != nil {
+		return v.errorAt(c, err)
+	}
+	v.links[c.KeyString()] = links
+
+	parent := v.paths[c.KeyString()]
+	for _, l := range links {
+		if _, ok := v.paths[l.cid.KeyString()]; ok || parent == "" {
+			continue
+		}
+		// Chunks of a file have no name and share the path of the file.
+		p := parent
+		if l.name != "" {
+			p += "/" + l.name
+		}
+		v.paths[l.cid.KeyString()] = p
+	}
+	return nil
+}
+
+// Complete checks that every block of the DAG under the root was added.
+func (v *Verifier) Complete() error {
+	if missing := v.Missing(); len(missing) > 0 {
+		return v.errorAt(missing[0], ErrMissingBlock)
+	}
+	return nil
+}
+
+// Missing lists the blocks not added yet whose parents are all known, that
+// is the blocks to fetch next to complete the DAG.
+func (v *Verifier) Missing() []cid.Cid {
+	var missing []cid.Cid
+	seen := make(map[string]bool)
+	queue := []cid.Cid{v.root}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if seen[c.KeyString()] {
+			continue
+		}
+		seen[c.KeyString()] = true
+
+		links, ok := v.links[c.KeyString()]
+		if !ok {
+			if c.Prefix().MhType != mh.IDENTITY {
+				missing = append(missing, c)
+			}
+			continue
+		}
+		for _, l := range links {
+			queue = append(queue, l.cid)
+		}
+	}
+	return missing
+}
+
+func (v *Verifier) errorAt(c cid.Cid, err error) *VerificationError {
+	return &VerificationError{Root: v.root, Cid: c, Path: v.paths[c.KeyString()], Err: err}
+}
+
+// VerifyCar re-hashes every block of the CAR stream r and checks that it
+// holds the complete DAG under root. An undefined root verifies the single
+// root of the CAR header.
+func VerifyCar(r io.Reader, root cid.Cid) error {
+	cr, err := carv1.NewCarReader(r)
+	if err != nil {
+		return err
+	}
+
+	if !root.Defined() {
+		if len(cr.Header.Roots) != 1 {
+			return fmt.Errorf("car file has %d roots, expected 1", len(cr.Header.Roots))
+		}
+		root = cr.Header.Roots[0]
+	}
+	found := false
+	for _, c := range cr.Header.Roots {
+		found = found || c.Equals(root)
+	}
+	if !found {
+		return &VerificationError{Root: root, Err: ErrUnrelatedRoot}
+	}
+
+	v := NewVerifier(root)
+	for {
+		blk, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := v.Add(blk); err != nil {
+			return err
+		}
+	}
+	return v.Complete()
+}
+
+// blockLinks decodes the links of a block. UnixFS links keep their names so
+// errors can point at the file they belong to.
+func blockLinks(c cid.Cid, data []byte) ([]dagLink, error) {
+	switch c.Type() {
+	case cid.Raw:
+		return nil, nil
+	case cid.DagProtobuf:
+		nd, err := merkledag.DecodeProtobuf(data)
+		if err != nil {
+			return nil, err
+		}
+		links := make([]dagLink, 0, len(nd.Links()))
+		for _, l := range nd.Links() {
+			links = append(links, dagLink{name: l.Name, cid: l.Cid})
+		}
+		return links, nil
+	}
+
+	decode, err := multicodec.LookupDecoder(c.Type())
+	if err != nil {
+		return nil, fmt.Errorf("%w %d", ErrUnknownCodec, c.Type())
+	}
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := decode(nb, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	var links []dagLink
+	err = collectLinks(nb.Build(), "", &links)
+	return links, err
+}
+
+func collectLinks(n datamodel.Node, path string, links *[]dagLink) error {
+	join := func(seg string) string {
+		if path == "" {
+			return seg
+		}
+		return path + "/" + seg
+	}
+
+	switch n.Kind() {
+	case datamodel.Kind_Link:
+		l, err := n.AsLink()
+		if err != nil {
+			return err
+		}
+		if cl, ok := l.(cidlink.Link); ok {
+			*links = append(*links, dagLink{name: path, cid: cl.Cid})
+		}
+	case datamodel.Kind_Map:
+		it := n.MapIterator()
+		for !it.Done() {
+			k, v, err := it.Next()
+			if err != nil {
+				return err
+			}
+			key, err := k.AsString()
+			if err != nil {
+				return err
+			}
+			if err := collectLinks(v, join(key), links); err != nil {
+				return err
+			}
+		}
+	case datamodel.Kind_List:
+		it := n.ListIterator()
+		for !it.Done() {
+			i, v, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if err := collectLinks(v, join(strconv.FormatInt(i, 10)), links); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -2,27 +2,46 @@ package cid
 
 import (
 	"context"
+	"github.com/ipfs/boxo/files"
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log"
+	"github.com/urchinfs/go-urchin2-sdk/cid/options"
 	"github.com/urchinfs/go-urchin2-sdk/utils"
 	"os"
 )
 
 var log = logging.Logger("cid")
 
-func GetCid(path string) (cid.Cid, error) {
-	_, err := os.Stat(path)
+// GetCid computes, without uploading anything, the CID the node would produce
+// for `client.Add` or `client.AddDir` of path with the same options: the
+// AddResult.Wrapper CID by default, or AddResult.Root when wrapping is
+// disabled with options.Unixfs.Wrap(false).
+func GetCid(path string, opts ...options.UnixfsAddOption) (cid.Cid, error) {
+	settings, _, err := options.UnixfsAddOptions(opts...)
+	if err != nil {
+		return cid.Cid{}, err
+	}
+
+	_, err = os.Stat(path)
 	if err != nil {
 		log.Errorf("get path stat err:%v", err)
 		return cid.Cid{}, err
 	}
 
-	wrapDataDir, err := utils.WarpPath(path)
+	var node files.Node
+	if settings.Wrap {
+		node, err = utils.WarpPath(path)
+	} else {
+		var filter *files.Filter
+		if filter, err = files.NewFilter("", nil, false); err == nil {
+			node, err = AppendFile(path, true, filter)
+		}
+	}
 	if err != nil {
 		return cid.Cid{}, err
 	}
 
-	rootCid, err := AddAndBuildCid(context.Background(), wrapDataDir)
+	rootCid, err := AddAndBuildCid(context.Background(), node, opts...)
 	if err != nil {
 		return cid.Cid{}, err
 	}
@@ -28,6 +28,16 @@ type UnixfsAddSettings struct {
 	Chunker string
 	Layout  Layout
 
+	OnlyHash      bool
+	NoCopy        bool
+	FsCache       bool
+	Wrap          bool
+	ToFiles       string
+	PreserveMode  bool
+	PreserveMtime bool
+
+	Pin bool
+
 	Events chan<- interface{}
 	Silent bool
 }
@@ -54,7 +64,18 @@ func UnixfsAddOptions(opts ...UnixfsAddOption) (*UnixfsAddSettings, cid.Prefix,
 
 		Chunker: "size-262144",
 		Layout:  BalancedLayout,
-		Events:  nil,
+
+		OnlyHash:      false,
+		NoCopy:        false,
+		FsCache:       false,
+		Wrap:          true,
+		ToFiles:       "",
+		PreserveMode:  false,
+		PreserveMtime: false,
+
+		Pin: true,
+
+		Events: nil,
 	}
 
 	for _, opt := range opts {
@@ -84,6 +105,13 @@ func UnixfsAddOptions(opts ...UnixfsAddOption) (*UnixfsAddSettings, cid.Prefix,
 		options.RawLeaves = true
 	}
 
+	if options.NoCopy {
+		if options.RawLeavesSet && !options.RawLeaves {
+			return nil, cid.Prefix{}, errors.New("nocopy requires raw leaves")
+		}
+		options.RawLeaves = true
+	}
+
 	prefix, err := dag.PrefixForCidVersion(options.CidVersion)
 	if err != nil {
 		return nil, cid.Prefix{}, err
@@ -136,6 +164,16 @@ func (unixfsOpts) RawLeaves(enable bool) UnixfsAddOption {
 	}
 }
 
+func (unixfsOpts) Trickle(enable bool) UnixfsAddOption {
+	return func(settings *UnixfsAddSettings) error {
+		settings.Layout = BalancedLayout
+		if enable {
+			settings.Layout = TrickleLayout
+		}
+		return nil
+	}
+}
+
 func (unixfsOpts) Inline(enable bool) UnixfsAddOption {
 	return func(settings *UnixfsAddSettings) error {
 		settings.Inline = enable
@@ -164,6 +202,73 @@ func (unixfsOpts) Layout(layout Layout) UnixfsAddOption {
 	}
 }
 
+// HashOnly computes the CID without writing the blocks to the node.
+func (unixfsOpts) HashOnly(hashOnly bool) UnixfsAddOption {
+	return func(settings *UnixfsAddSettings) error {
+		settings.OnlyHash = hashOnly
+		return nil
+	}
+}
+
+// Nocopy adds files through the node's filestore instead of copying them into
+// its blockstore. It implies raw leaves.
+func (unixfsOpts) Nocopy(enable bool) UnixfsAddOption {
+	return func(settings *UnixfsAddSettings) error {
+		settings.NoCopy = enable
+		return nil
+	}
+}
+
+// FsCache checks the node's filestore for pre-existing blocks.
+func (unixfsOpts) FsCache(enable bool) UnixfsAddOption {
+	return func(settings *UnixfsAddSettings) error {
+		settings.FsCache = enable
+		return nil
+	}
+}
+
+// Wrap wraps the added content in a directory, which is the default. Without
+// it the returned CID is the one of the content itself.
+func (unixfsOpts) Wrap(wrap bool) UnixfsAddOption {
+	return func(settings *UnixfsAddSettings) error {
+		settings.Wrap = wrap
+		return nil
+	}
+}
+
+// ToFiles copies the added content to the given MFS path on the node.
+func (unixfsOpts) ToFiles(path string) UnixfsAddOption {
+	return func(settings *UnixfsAddSettings) error {
+		settings.ToFiles = path
+		return nil
+	}
+}
+
+// PreserveMode stores the file mode in the UnixFS metadata.
+func (unixfsOpts) PreserveMode(enable bool) UnixfsAddOption {
+	return func(settings *UnixfsAddSettings) error {
+		settings.PreserveMode = enable
+		return nil
+	}
+}
+
+// PreserveMtime stores the modification time in the UnixFS metadata.
+func (unixfsOpts) PreserveMtime(enable bool) UnixfsAddOption {
+	return func(settings *UnixfsAddSettings) error {
+		settings.PreserveMtime = enable
+		return nil
+	}
+}
+
+// Pin tells the node whether to pin the added content. It has no effect on
+// locally computed CIDs.
+func (unixfsOpts) Pin(pin bool) UnixfsAddOption {
+	return func(settings *UnixfsAddSettings) error {
+		settings.Pin = pin
+		return nil
+	}
+}
+
 func (unixfsOpts) Events(sink chan<- interface{}) UnixfsAddOption {
 	return func(settings *UnixfsAddSettings) error {
 		settings.Events = sink
@@ -11,6 +11,7 @@ import (
 	"github.com/ipfs/boxo/mfs"
 	"github.com/ipfs/boxo/path"
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-cidutil"
 	ds "github.com/ipfs/go-datastore"
 	dssync "github.com/ipfs/go-datastore/sync"
 	ipld "github.com/ipfs/go-ipld-format"
@@ -67,6 +68,9 @@ func AddAndBuildCid(ctx context.Context, files files.Node, opts ...options.Unixf
 	if err != nil {
 		return cid.Cid{}, err
 	}
+	if settings.PreserveMode || settings.PreserveMtime {
+		return cid.Cid{}, utils.ErrNotSupported
+	}
 
 	dstore := dssync.MutexWrap(ds.NewNullDatastore())
 	bs := bstore.NewBlockstore(dstore, bstore.WriteThrough())
@@ -91,7 +95,15 @@ func AddAndBuildCid(ctx context.Context, files files.Node, opts ...options.Unixf
 	}
 	fileAdder.Silent = settings.Silent
 	fileAdder.RawLeaves = settings.RawLeaves
+	fileAdder.NoCopy = settings.NoCopy
+	fileAdder.Trickle = settings.Layout == options.TrickleLayout
 	fileAdder.CidBuilder = prefix
+	if settings.Inline {
+		fileAdder.CidBuilder = cidutil.InlineBuilder{
+			Builder: prefix,
+			Limit:   settings.InlineLimit,
+		}
+	}
 
 	md := dagtest.Mock()
 	emptyDirNode := ft.EmptyDirNode()
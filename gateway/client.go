@@ -0,0 +1,324 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/boxo/blockstore"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log"
+	carv1 "github.com/ipld/go-car"
+	"github.com/urchinfs/go-urchin2-sdk/car"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+	"github.com/urchinfs/go-urchin2-sdk/utils"
+)
+
+var log = logging.Logger("gateway")
+
+const (
+	carContentType = "application/vnd.ipld.car"
+	rawContentType = "application/vnd.ipld.raw"
+
+	// blockConcurrency is the number of blocks fetched at the same time
+	// when the gateway cannot send the missing part of a DAG as a CAR.
+	blockConcurrency = 8
+	progressStep     = 1 << 20
+)
+
+var _ ipfs_api.Downloader = (*Client)(nil)
+
+// Error is a failure reported by the gateway.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("gateway: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("gateway: %d: %s", e.StatusCode, e.Message)
+}
+
+// Client downloads content from an HTTP gateway through the trustless
+// gateway protocol (https://specs.ipfs.tech/http-gateways/trustless-gateway/).
+// Gateways are not trusted: every block is checked against its CID.
+type Client struct {
+	url     string
+	httpCli *http.Client
+}
+
+// NewClient creates a client for the gateway at url, e.g. https://ipfs.io.
+func NewClient(url string) *Client {
+	return &Client{
+		url:     strings.TrimRight(url, "/"),
+		httpCli: http.DefaultClient,
+	}
+}
+
+// SetHTTPClient replaces the client used to talk to the gateway.
+func (c *Client) SetHTTPClient(httpCli *http.Client) {
+	c.httpCli = httpCli
+}
+
+func (c *Client) request(ctx context.Context, p, accept string, query url.Values) (*http.Response, error) {
+	u := c.url + "/ipfs/" + strings.TrimPrefix(p, "/ipfs/")
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := c.httpCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	return resp, nil
+}
+
+// Block fetches the raw block c and checks it against c.
+func (c *Client) Block(ctx context.Context, id cid.Cid) (blocks.Block, error) {
+	resp, err := c.request(ctx, id.String(), rawContentType, url.Values{"format": {"raw"}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := id.Prefix().Sum(data)
+	if err != nil {
+		return nil, err
+	}
+	if !sum.Equals(id) {
+		return nil, &car.VerificationError{Root: id, Cid: id, Err: car.ErrHashMismatch}
+	}
+	return blocks.NewBlockWithCid(data, id)
+}
+
+// Car streams the CAR response for p, a CID optionally followed by a path.
+// The blocks are not verified, see Fetch.
+func (c *Client) Car(ctx context.Context, p string, opts ...CarOption) (io.ReadCloser, error) {
+	settings, err := CarOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{
+		"format":    {"car"},
+		"dag-scope": {settings.Scope},
+	}
+	if settings.EntityBytes != "" {
+		query.Set("entity-bytes", settings.EntityBytes)
+	}
+
+	resp, err := c.request(ctx, p, carContentType+";version=1;order=dfs;dups=n", query)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// fetcher gathers the verified blocks of a DAG into a blockstore and
+// reports the bytes received.
+type fetcher struct {
+	c  *Client
+	bs blockstore.Blockstore
+	v  *car.Verifier
+
+	mu       sync.Mutex
+	events   chan<- interface{}
+	ev       ipfs_api.DownloadEvent
+	reported int64
+}
+
+func (f *fetcher) add(ctx context.Context, blk blocks.Block) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.v.Add(blk); err != nil {
+		return err
+	}
+	if err := f.bs.Put(ctx, blk); err != nil {
+		return err
+	}
+
+	f.ev.Bytes += int64(len(blk.RawData()))
+	if f.events != nil && f.ev.Bytes-f.reported >= progressStep {
+		return f.emit(ctx)
+	}
+	return nil
+}
+
+func (f *fetcher) emit(ctx context.Context) error {
+	ev := f.ev
+	f.reported = ev.Bytes
+	select {
+	case f.events <- &ev:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// car loads the CAR response for the whole DAG. A gateway failing halfway
+// is not fatal, the missing blocks are fetched one by one afterwards.
+func (f *fetcher) car(ctx context.Context, root cid.Cid) error {
+	body, err := f.c.Car(ctx, root.String())
+	if err != nil {
+		var gerr *Error
+		if errors.As(err, &gerr) {
+			log.Warnf("gateway refused CAR for %s, fetching blocks: %v", root, err)
+			return nil
+		}
+		return err
+	}
+	defer body.Close()
+
+	cr, err := carv1.NewCarReader(bufio.NewReader(body))
+	if err != nil {
+		log.Warnf("bad CAR response for %s, fetching blocks: %v", root, err)
+		return nil
+	}
+	for {
+		blk, err := cr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Warnf("CAR response for %s broke, fetching missing blocks: %v", root, err)
+			return nil
+		}
+		if err := f.add(ctx, blk); err != nil {
+			return err
+		}
+	}
+}
+
+// blocks fetches the missing blocks of the DAG one request per block until
+// it is complete.
+func (f *fetcher) blocks(ctx context.Context) error {
+	for {
+		f.mu.Lock()
+		missing := f.v.Missing()
+		f.mu.Unlock()
+		if len(missing) == 0 {
+			return nil
+		}
+
+		sem := make(chan struct{}, blockConcurrency)
+		errs := make(chan error, len(missing))
+		var wg sync.WaitGroup
+		for _, id := range missing {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(id cid.Cid) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				blk, err := f.c.Block(ctx, id)
+				if err == nil {
+					err = f.add(ctx, blk)
+				}
+				if err != nil {
+					errs <- err
+				}
+			}(id)
+		}
+		wg.Wait()
+		close(errs)
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+}
+
+// Fetch downloads the complete DAG under root into bs, checking every block
+// against its CID. It asks for a CAR first and falls back to single blocks
+// for whatever the CAR response did not hold.
+func (c *Client) Fetch(ctx context.Context, root cid.Cid, bs blockstore.Blockstore) error {
+	f := &fetcher{c: c, bs: bs, v: car.NewVerifier(root)}
+	return f.fetch(ctx, root)
+}
+
+func (f *fetcher) fetch(ctx context.Context, root cid.Cid) error {
+	if err := f.car(ctx, root); err != nil {
+		return err
+	}
+	if err := f.blocks(ctx); err != nil {
+		return err
+	}
+	return f.v.Complete()
+}
+
+// Get downloads hash, which must be a CID, and writes its files to outDir
+// like HttpClient.Get. The content is always verified; resuming is not
+// supported. Blocks are held in memory until the files are written.
+func (c *Client) Get(ctx context.Context, hash, outDir string, opts ...options.DownloadOption) error {
+	settings, err := options.DownloadOptions(opts...)
+	if err != nil {
+		return err
+	}
+	if settings.Resume {
+		return utils.ErrNotSupported
+	}
+
+	root, err := cid.Decode(strings.TrimPrefix(hash, "/ipfs/"))
+	if err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(outDir)
+	if err != nil {
+		return err
+	}
+	if stat.IsDir() {
+		outDir = path.Join(outDir, hash)
+	}
+
+	bs := car.NewMemBlockstore()
+	f := &fetcher{
+		c:      c,
+		bs:     bs,
+		v:      car.NewVerifier(root),
+		events: settings.Events,
+		ev:     ipfs_api.DownloadEvent{Path: hash},
+	}
+	if err := f.fetch(ctx, root); err != nil {
+		return err
+	}
+
+	if err := car.RestoreDag(ctx, bs, root, outDir); err != nil {
+		return err
+	}
+	if settings.Events != nil {
+		f.ev.Done = true
+		return f.emit(ctx)
+	}
+	return nil
+}
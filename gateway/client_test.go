@@ -0,0 +1,256 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/urchinfs/go-urchin2-sdk/car"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+	"github.com/urchinfs/go-urchin2-sdk/utils"
+)
+
+// testFiles is the tree packed by writeTestCar, by path relative to its
+// root.
+var testFiles = map[string][]byte{
+	"a.txt":       []byte("hello world"),
+	".hidden":     []byte("hidden"),
+	"sub/big.bin": randomBytes(600000),
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(b)
+	return b
+}
+
+// writeTestCar packs testFiles, in a directory named tree, into a CARv1
+// file and returns its path and root.
+func writeTestCar(t *testing.T) (string, cid.Cid) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "tree")
+	for name, data := range testFiles {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v1car, err := car.NewBuilder().BuildCar(context.Background(), dir, car.ImportOpts.IncludeHiddenFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "tree.car")
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := v1car.Write(f); err != nil {
+		t.Fatal(err)
+	}
+	return out, v1car.Root()
+}
+
+// newTestGateway serves the test tree, passing every request through wrap
+// when it is set.
+func newTestGateway(t *testing.T, wrap func(next http.Handler) http.Handler) (*Client, cid.Cid) {
+	t.Helper()
+	carFile, root := writeTestCar(t)
+	s, err := NewServer(carFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	var h http.Handler = s
+	if wrap != nil {
+		h = wrap(s)
+	}
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	return NewClient(ts.URL + "/"), root
+}
+
+// checkFiles compares the tree Get wrote under dir with testFiles. The
+// builder wraps the packed directory, so the tree sits below the root.
+func checkFiles(t *testing.T, dir string, root cid.Cid) {
+	t.Helper()
+	dir = filepath.Join(dir, root.String(), "tree")
+	for name, want := range testFiles {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: got %d bytes, want %d", name, len(got), len(want))
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	var carRequests, blockRequests atomic.Int32
+	c, root := newTestGateway(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Query().Get("format") {
+			case "car":
+				carRequests.Add(1)
+			case "raw":
+				blockRequests.Add(1)
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	events := make(chan interface{}, 64)
+	dir := t.TempDir()
+	if err := c.Get(context.Background(), root.String(), dir, options.Download.Events(events)); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, root)
+
+	if carRequests.Load() != 1 || blockRequests.Load() != 0 {
+		t.Errorf("%d CAR and %d block requests, want the whole DAG in one CAR", carRequests.Load(), blockRequests.Load())
+	}
+	close(events)
+	var last *ipfs_api.DownloadEvent
+	for ev := range events {
+		last = ev.(*ipfs_api.DownloadEvent)
+	}
+	if last == nil || !last.Done || last.Bytes < 600000 {
+		t.Errorf("last event = %+v, want done after all blocks", last)
+	}
+}
+
+func TestGetFallsBackToBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		car  func(w http.ResponseWriter, r *http.Request, next http.Handler)
+	}{
+		{
+			name: "CAR refused",
+			car: func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+				http.Error(w, "not acceptable", http.StatusNotAcceptable)
+			},
+		},
+		{
+			name: "CAR broken halfway",
+			car: func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+				rec := httptest.NewRecorder()
+				next.ServeHTTP(rec, r)
+				body := rec.Body.Bytes()
+				w.Write(body[:len(body)/2])
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var blockRequests atomic.Int32
+			c, root := newTestGateway(t, func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Query().Get("format") {
+					case "car":
+						tt.car(w, r, next)
+						return
+					case "raw":
+						blockRequests.Add(1)
+					}
+					next.ServeHTTP(w, r)
+				})
+			})
+
+			dir := t.TempDir()
+			if err := c.Get(context.Background(), root.String(), dir); err != nil {
+				t.Fatal(err)
+			}
+			checkFiles(t, dir, root)
+			if blockRequests.Load() == 0 {
+				t.Error("no block fetched one by one")
+			}
+		})
+	}
+}
+
+func TestGetRejectsBadBlock(t *testing.T) {
+	c, root := newTestGateway(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Query().Get("format") {
+			case "car":
+				http.Error(w, "not acceptable", http.StatusNotAcceptable)
+			case "raw":
+				w.Write([]byte("not the block"))
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	})
+
+	_, err := c.Block(context.Background(), root)
+	var verr *car.VerificationError
+	if !errors.As(err, &verr) || !errors.Is(err, car.ErrHashMismatch) {
+		t.Fatalf("block = %v, want a hash mismatch", err)
+	}
+
+	dir := t.TempDir()
+	if err := c.Get(context.Background(), root.String(), dir); !errors.Is(err, car.ErrHashMismatch) {
+		t.Fatalf("get = %v, want a hash mismatch", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, root.String())); !os.IsNotExist(err) {
+		t.Error("files of unverified content written")
+	}
+}
+
+func TestCar(t *testing.T) {
+	var query string
+	c, root := newTestGateway(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			next.ServeHTTP(w, r)
+		})
+	})
+	ctx := context.Background()
+
+	body, err := c.Car(ctx, root.String()+"/sub/big.bin", Car.EntityBytes(0, 999))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	if want := "dag-scope=entity&entity-bytes=0%3A999&format=car"; query != want {
+		t.Errorf("query = %s, want %s", query, want)
+	}
+
+	if _, err := c.Car(ctx, root.String(), Car.Scope("files")); err == nil {
+		t.Error("unknown scope accepted")
+	}
+	if _, err := c.Car(ctx, root.String(), Car.EntityBytes(10, 5)); err == nil {
+		t.Error("reversed range accepted")
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	c, root := newTestGateway(t, nil)
+	ctx := context.Background()
+
+	if err := c.Get(ctx, root.String(), t.TempDir(), options.Download.Resume(true)); err != utils.ErrNotSupported {
+		t.Errorf("resume = %v, want ErrNotSupported", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := c.Get(ctx, root.String(), t.TempDir()); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled get = %v", err)
+	}
+}
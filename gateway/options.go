@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type CarSettings struct {
+	Scope       string
+	EntityBytes string
+}
+
+type CarOption func(*CarSettings) error
+
+func CarOptions(opts ...CarOption) (*CarSettings, error) {
+	options := &CarSettings{
+		Scope:       "all",
+		EntityBytes: "",
+	}
+
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type carOpts struct{}
+
+var Car carOpts
+
+// Scope is the dag-scope of the CAR response: "all" (default) for the whole
+// DAG, "entity" for the blocks of the file or directory the path points to,
+// or "block" for the last block of the path only.
+func (carOpts) Scope(scope string) CarOption {
+	return func(opts *CarSettings) error {
+		switch scope {
+		case "all", "entity", "block":
+		default:
+			return fmt.Errorf("unknown dag scope %q", scope)
+		}
+		opts.Scope = scope
+		return nil
+	}
+}
+
+// EntityBytes restricts an "entity" scoped response to the blocks of the
+// byte range from-to of the file, both included. A negative to reads up to
+// the end of the file, negative offsets count from the end.
+func (carOpts) EntityBytes(from, to int64) CarOption {
+	return func(opts *CarSettings) error {
+		end := "*"
+		if to >= 0 {
+			if from >= 0 && to < from {
+				return fmt.Errorf("invalid entity bytes %d:%d", from, to)
+			}
+			end = strconv.FormatInt(to, 10)
+		}
+		opts.Scope = "entity"
+		opts.EntityBytes = strconv.FormatInt(from, 10) + ":" + end
+		return nil
+	}
+}
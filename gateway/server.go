@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ipfs/boxo/blockservice"
+	"github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/exchange/offline"
+	boxogw "github.com/ipfs/boxo/gateway"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	carbs "github.com/ipld/go-car/v2/blockstore"
+)
+
+var ErrReadOnly = errors.New("car blockstore is read only")
+
+// Server is a trustless gateway serving the content of CAR files, such as
+// the ones written by car.PackCarFormat, without an IPFS node. Besides raw
+// blocks and CAR responses it serves UnixFS files, with range requests, and
+// directory listings.
+type Server struct {
+	cars    []*carbs.ReadOnly
+	roots   []cid.Cid
+	handler http.Handler
+}
+
+var _ http.Handler = (*Server)(nil)
+
+// NewServer opens the given CAR files. CARv2 files are served through their
+// index; CARv1 files are indexed in memory when opened.
+func NewServer(carFiles ...string) (*Server, error) {
+	s := &Server{}
+	for _, file := range carFiles {
+		bs, err := carbs.OpenReadOnly(file)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.cars = append(s.cars, bs)
+
+		roots, err := bs.Roots()
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.roots = append(s.roots, roots...)
+	}
+
+	bs := &carBlockstore{cars: s.cars}
+	backend, err := boxogw.NewBlocksBackend(blockservice.New(bs, offline.Exchange(bs)))
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	handler := boxogw.NewHandler(boxogw.Config{
+		DeserializedResponses: true,
+		NoDNSLink:             true,
+	}, backend)
+	mux := http.NewServeMux()
+	mux.Handle("/ipfs/", handler)
+	s.handler = mux
+	return s, nil
+}
+
+// Roots returns the roots of all the CAR files served.
+func (s *Server) Roots() []cid.Cid {
+	return s.roots
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Close closes the CAR files.
+func (s *Server) Close() error {
+	var errs []error
+	for _, bs := range s.cars {
+		errs = append(errs, bs.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// carBlockstore serves blocks from several read only CAR blockstores, the
+// first one holding a block wins.
+type carBlockstore struct {
+	cars []*carbs.ReadOnly
+}
+
+var _ blockstore.Blockstore = (*carBlockstore)(nil)
+
+func (b *carBlockstore) Has(ctx context.Context, c cid.Cid) (bool, error) {
+	for _, bs := range b.cars {
+		has, err := bs.Has(ctx, c)
+		if err != nil || has {
+			return has, err
+		}
+	}
+	return false, nil
+}
+
+func (b *carBlockstore) Get(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	for _, bs := range b.cars {
+		blk, err := bs.Get(ctx, c)
+		if !format.IsNotFound(err) {
+			return blk, err
+		}
+	}
+	return nil, format.ErrNotFound{Cid: c}
+}
+
+func (b *carBlockstore) GetSize(ctx context.Context, c cid.Cid) (int, error) {
+	for _, bs := range b.cars {
+		size, err := bs.GetSize(ctx, c)
+		if !format.IsNotFound(err) {
+			return size, err
+		}
+	}
+	return -1, format.ErrNotFound{Cid: c}
+}
+
+func (b *carBlockstore) DeleteBlock(context.Context, cid.Cid) error {
+	return ErrReadOnly
+}
+
+func (b *carBlockstore) Put(context.Context, blocks.Block) error {
+	return ErrReadOnly
+}
+
+func (b *carBlockstore) PutMany(context.Context, []blocks.Block) error {
+	return ErrReadOnly
+}
+
+func (b *carBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	out := make(chan cid.Cid)
+	go func() {
+		defer close(out)
+		for _, bs := range b.cars {
+			keys, err := bs.AllKeysChan(ctx)
+			if err != nil {
+				log.Errorf("list car blocks: %v", err)
+				return
+			}
+			for c := range keys {
+				select {
+				case out <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (b *carBlockstore) HashOnRead(enabled bool) {
+	for _, bs := range b.cars {
+		bs.HashOnRead(enabled)
+	}
+}
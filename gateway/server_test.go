@@ -0,0 +1,222 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	carv2 "github.com/ipld/go-car/v2"
+	"github.com/urchinfs/go-urchin2-sdk/car"
+)
+
+// writeFileCar packs a single file into a CARv1 file and returns its path
+// and root.
+func writeFileCar(t *testing.T, name string, data []byte) (string, cid.Cid) {
+	t.Helper()
+	in := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(in, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	v1car, err := car.NewBuilder().BuildCar(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), name+".car")
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := v1car.Write(f); err != nil {
+		t.Fatal(err)
+	}
+	return out, v1car.Root()
+}
+
+func newTestServer(t *testing.T, carFiles ...string) string {
+	t.Helper()
+	s, err := NewServer(carFiles...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func get(t *testing.T, url string, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func TestServerRoots(t *testing.T) {
+	treeCar, treeRoot := writeTestCar(t)
+	fileCar, fileRoot := writeFileCar(t, "other.txt", []byte("other"))
+
+	// The second CAR is served through a CARv2 index.
+	v2Car := filepath.Join(t.TempDir(), "other.v2.car")
+	if err := carv2.WrapV1File(fileCar, v2Car); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(treeCar, v2Car)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if roots := s.Roots(); len(roots) != 2 || !roots[0].Equals(treeRoot) || !roots[1].Equals(fileRoot) {
+		t.Errorf("roots = %v, want [%s %s]", roots, treeRoot, fileRoot)
+	}
+
+	url := newTestServer(t, treeCar, v2Car)
+	for p, want := range map[string]string{
+		"/ipfs/" + treeRoot.String() + "/tree/a.txt":   "hello world",
+		"/ipfs/" + fileRoot.String() + "/other.txt":    "other",
+		"/ipfs/" + treeRoot.String() + "/tree/.hidden": "hidden",
+	} {
+		resp, body := get(t, url+p, nil)
+		if resp.StatusCode != http.StatusOK || string(body) != want {
+			t.Errorf("%s: %s %q, want %q", p, resp.Status, body, want)
+		}
+	}
+}
+
+func TestServerRange(t *testing.T) {
+	carFile, root := writeTestCar(t)
+	url := newTestServer(t, carFile)
+
+	resp, body := get(t, url+"/ipfs/"+root.String()+"/tree/sub/big.bin", http.Header{"Range": {"bytes=300000-300099"}})
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("status %s, want 206", resp.Status)
+	}
+	if want := testFiles["sub/big.bin"][300000:300100]; !bytes.Equal(body, want) {
+		t.Errorf("range returned %d bytes not matching the file", len(body))
+	}
+}
+
+func TestServerDirectoryListing(t *testing.T) {
+	carFile, root := writeTestCar(t)
+	url := newTestServer(t, carFile)
+
+	resp, body := get(t, url+"/ipfs/"+root.String()+"/tree/", http.Header{"Accept": {"text/html"}})
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte("a.txt")) {
+		t.Errorf("listing: %s, want a page naming a.txt", resp.Status)
+	}
+}
+
+func TestServerRawBlock(t *testing.T) {
+	carFile, root := writeTestCar(t)
+	url := newTestServer(t, carFile)
+
+	resp, body := get(t, url+"/ipfs/"+root.String()+"?format=raw", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %s", resp.Status)
+	}
+	if _, err := blocks.NewBlockWithCid(body, root); err != nil {
+		t.Errorf("raw block does not hash to the root: %v", err)
+	}
+}
+
+func TestServerCar(t *testing.T) {
+	carFile, root := writeTestCar(t)
+	url := newTestServer(t, carFile)
+
+	resp, body := get(t, url+"/ipfs/"+root.String()+"?format=car", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %s", resp.Status)
+	}
+	br, err := carv2.NewBlockReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(br.Roots) != 1 || !br.Roots[0].Equals(root) {
+		t.Errorf("roots = %v, want [%s]", br.Roots, root)
+	}
+
+	v := car.NewVerifier(root)
+	n := 0
+	for {
+		blk, err := br.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := v.Add(blk); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if err := v.Complete(); err != nil {
+		t.Errorf("%d blocks: %v", n, err)
+	}
+}
+
+func TestServerMissingContent(t *testing.T) {
+	carFile, _ := writeTestCar(t)
+	url := newTestServer(t, carFile)
+	_, other := writeFileCar(t, "other.txt", []byte("not served"))
+
+	for _, q := range []string{"", "?format=raw"} {
+		resp, _ := get(t, url+"/ipfs/"+other.String()+q, nil)
+		if resp.StatusCode == http.StatusOK {
+			t.Errorf("%q: status 200 for content missing from the CARs served", q)
+		}
+	}
+
+	// CAR responses are streamed, the status is sent before the first
+	// block is looked up.
+	resp, body := get(t, url+"/ipfs/"+other.String()+"?format=car", nil)
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	br, err := carv2.NewBlockReader(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	if blk, err := br.Next(); err == nil {
+		t.Errorf("CAR of missing content holds %s", blk.Cid())
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	if _, err := NewServer(filepath.Join(t.TempDir(), "missing.car")); err == nil {
+		t.Error("missing CAR file opened")
+	}
+
+	carFile, _ := writeTestCar(t)
+	s, err := NewServer(carFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	bs := &carBlockstore{cars: s.cars}
+	if err := bs.Put(context.Background(), blocks.NewBlock([]byte("x"))); err != ErrReadOnly {
+		t.Errorf("put = %v, want ErrReadOnly", err)
+	}
+}
@@ -0,0 +1,66 @@
+package ipfs_api
+
+import (
+	"context"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// tokenRefreshLeeway is how long before its expiry a token is replaced.
+const tokenRefreshLeeway = 30 * time.Second
+
+// TokenFetcher obtains a new bearer token and the time it expires at. A zero
+// expiry means the token never expires.
+type TokenFetcher func(ctx context.Context) (token string, expiry time.Time, err error)
+
+type refreshingTokenSource struct {
+	fetch TokenFetcher
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewRefreshingTokenSource returns a token source that caches the token from
+// fetch and calls fetch again shortly before the token expires.
+func NewRefreshingTokenSource(fetch TokenFetcher) options.TokenSource {
+	return &refreshingTokenSource{fetch: fetch}
+}
+
+func (s *refreshingTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && (s.expiry.IsZero() || time.Until(s.expiry) > tokenRefreshLeeway) {
+		return s.token, nil
+	}
+
+	token, expiry, err := s.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = token
+	s.expiry = expiry
+	return token, nil
+}
+
+// authorization returns the Authorization header value for a request, or an
+// empty string when the client does not authenticate.
+func (h *HttpClient) authorization(ctx context.Context) (string, error) {
+	switch {
+	case h.tokens != nil:
+		token, err := h.tokens.Token(ctx)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	case h.username != "":
+		cred := base64.StdEncoding.EncodeToString([]byte(h.username + ":" + h.password))
+		return "Basic " + cred, nil
+	default:
+		return "", nil
+	}
+}
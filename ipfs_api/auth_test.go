@@ -0,0 +1,234 @@
+package ipfs_api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/testserver"
+)
+
+// authServer serves a test node and records the Authorization header of
+// every request.
+type authServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	headers []string
+}
+
+func newAuthServer(t *testing.T) *authServer {
+	t.Helper()
+	s := &authServer{}
+	node := testserver.NewUnstarted()
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.headers = append(s.headers, r.Header.Get("Authorization"))
+		s.mu.Unlock()
+		node.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *authServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.headers...)
+}
+
+type tokenFunc func(ctx context.Context) (string, error)
+
+func (f tokenFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+func TestAuthorization(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  options.ClientOption
+		want string
+	}{
+		{"none", options.Client.Header("X-Test", "1"), ""},
+		{"basic", options.Client.BasicAuth("user", "pass"), "Basic dXNlcjpwYXNz"},
+		{"bearer", options.Client.BearerToken("secret"), "Bearer secret"},
+		{"token source", options.Client.TokenSource(tokenFunc(func(context.Context) (string, error) {
+			return "fetched", nil
+		})), "Bearer fetched"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAuthServer(t)
+			c, err := NewClient(s.URL, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := c.Version(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.received(); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	_, err := NewClient("http://127.0.0.1:5001", options.Client.BasicAuth("user", "pass"), options.Client.BearerToken("secret"))
+	if err == nil {
+		t.Error("basic auth and bearer token accepted together")
+	}
+}
+
+func TestTokenSourceError(t *testing.T) {
+	s := newAuthServer(t)
+	fetchErr := errors.New("identity provider down")
+	c, err := NewClient(s.URL, options.Client.TokenSource(tokenFunc(func(context.Context) (string, error) {
+		return "", fetchErr
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRetryPolicy(fastRetry())
+
+	if _, _, err := c.Version(context.Background()); !errors.Is(err, fetchErr) {
+		t.Errorf("version = %v, want the token error", err)
+	}
+	if got := s.received(); len(got) != 0 {
+		t.Errorf("%d requests sent without a token", len(got))
+	}
+}
+
+func TestRefreshingTokenSource(t *testing.T) {
+	s := newAuthServer(t)
+	// The first token is replaced half a second from now, the second one
+	// never expires.
+	lifetimes := []time.Duration{tokenRefreshLeeway + 500*time.Millisecond, 0}
+	var fetches int
+	source := NewRefreshingTokenSource(func(context.Context) (string, time.Time, error) {
+		fetches++
+		token := fmt.Sprintf("token-%d", fetches)
+		if lifetimes[fetches-1] == 0 {
+			return token, time.Time{}, nil
+		}
+		return token, time.Now().Add(lifetimes[fetches-1]), nil
+	})
+	c, err := NewClient(s.URL, options.Client.TokenSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version := func() {
+		t.Helper()
+		if _, _, err := c.Version(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	version()
+	version()
+	time.Sleep(600 * time.Millisecond)
+	version()
+	version()
+
+	want := []string{"Bearer token-1", "Bearer token-1", "Bearer token-2", "Bearer token-2"}
+	got := s.received()
+	if len(got) != len(want) {
+		t.Fatalf("headers = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d: Authorization = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("%d fetches, want 2", fetches)
+	}
+}
+
+// writeClientCert writes a self-signed client certificate and its key to
+// PEM files and returns their paths with the certificate.
+func writeClientCert(t *testing.T) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestClientCertificate(t *testing.T) {
+	certFile, keyFile, cert := writeClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+
+	ts := httptest.NewUnstartedServer(testserver.NewUnstarted())
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	ts.StartTLS()
+	defer ts.Close()
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ts.Certificate())
+
+	c, err := NewClient(ts.URL, options.Client.RootCAs(rootCAs), options.Client.ClientCertificate(certFile, keyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Version(context.Background()); err != nil {
+		t.Errorf("version with a client certificate = %v", err)
+	}
+
+	c, err = NewClient(ts.URL, options.Client.RootCAs(rootCAs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRetryPolicy(fastRetry())
+	if _, _, err := c.Version(context.Background()); err == nil {
+		t.Error("server requiring a client certificate answered without one")
+	}
+
+	if _, err := NewClient(ts.URL, options.Client.ClientCertificate(keyFile, certFile)); err == nil {
+		t.Error("swapped certificate and key files accepted")
+	}
+}
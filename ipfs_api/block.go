@@ -0,0 +1,238 @@
+package ipfs_api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/boxo/files"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multicodec"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/urchinfs/go-urchin2-sdk/car"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// BlockStat is the size of a stored block.
+type BlockStat struct {
+	Cid  cid.Cid
+	Size int
+}
+
+// BlockGet streams the raw content of the block c. The content is not
+// checked against c, use Block for that.
+func (h *HttpClient) BlockGet(ctx context.Context, c cid.Cid) (io.ReadCloser, error) {
+	resp, err := h.Request("block/get", c.String()).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Output, nil
+}
+
+// Block fetches the block c and checks that its content hashes to c. A
+// mismatch is reported as a *car.VerificationError.
+func (h *HttpClient) Block(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+	r, err := h.BlockGet(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := c.Prefix().Sum(data)
+	if err != nil {
+		return nil, err
+	}
+	if !sum.Equals(c) {
+		return nil, &car.VerificationError{Root: c, Cid: c, Err: fmt.Errorf("%w: content hashes to %s", car.ErrHashMismatch, sum)}
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// BlockPut stores every element of data as a block in a single request and
+// returns the stored blocks in the same order.
+func (h *HttpClient) BlockPut(ctx context.Context, data [][]byte, opts ...options.BlockPutOption) ([]blocks.Block, error) {
+	settings, err := options.BlockPutOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	rb := h.Request("block/put").
+		Option("cid-codec", settings.Codec).
+		Option("mhtype", settings.MhType).
+		Option("mhlen", settings.MhLength).
+		Option("pin", settings.Pin).
+		Option("allow-big-block", settings.AllowBigBlock).
+		BodyFunc(func() (io.Reader, error) {
+			entries := make([]files.DirEntry, 0, len(data))
+			for _, d := range data {
+				entries = append(entries, files.FileEntry("", files.NewBytesFile(d)))
+			}
+			return h.newMultiFileReader(ctx, files.NewSliceDirectory(entries))
+		})
+
+	resp, err := rb.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	out := make([]blocks.Block, 0, len(data))
+	dec := json.NewDecoder(resp.Output)
+	for {
+		var stat struct {
+			Key  string
+			Size int
+		}
+		if err := dec.Decode(&stat); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(out) == len(data) {
+			return nil, errors.New("block/put: more blocks stored than sent")
+		}
+		c, err := cid.Decode(stat.Key)
+		if err != nil {
+			return nil, err
+		}
+		b, err := blocks.NewBlockWithCid(data[len(out)], c)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	if len(out) != len(data) {
+		return nil, fmt.Errorf("block/put: %d of %d blocks stored", len(out), len(data))
+	}
+	return out, nil
+}
+
+// BlockPutBlocks stores existing blocks, for instance read from a CAR file,
+// with one request per codec and hash function. The node must end up with
+// the same codec and multihash as every block. Only options.Block.Pin and
+// options.Block.AllowBigBlock are honoured.
+func (h *HttpClient) BlockPutBlocks(ctx context.Context, blks []blocks.Block, opts ...options.BlockPutOption) error {
+	type group struct {
+		prefix cid.Prefix
+		blks   []blocks.Block
+	}
+	var groups []*group
+	byPrefix := make(map[cid.Prefix]*group)
+	for _, b := range blks {
+		p := b.Cid().Prefix()
+		p.Version = 1
+		g, ok := byPrefix[p]
+		if !ok {
+			g = &group{prefix: p}
+			byPrefix[p] = g
+			groups = append(groups, g)
+		}
+		g.blks = append(g.blks, b)
+	}
+
+	for _, g := range groups {
+		hash, ok := mh.Codes[g.prefix.MhType]
+		if !ok {
+			return fmt.Errorf("unknown multihash type: %d", g.prefix.MhType)
+		}
+		data := make([][]byte, 0, len(g.blks))
+		for _, b := range g.blks {
+			data = append(data, b.RawData())
+		}
+
+		groupOpts := append([]options.BlockPutOption{}, opts...)
+		groupOpts = append(groupOpts,
+			options.Block.Codec(multicodec.Code(g.prefix.Codec).String()),
+			options.Block.Hash(hash, g.prefix.MhLength))
+		stored, err := h.BlockPut(ctx, data, groupOpts...)
+		if err != nil {
+			return err
+		}
+		for i, b := range stored {
+			want := g.blks[i].Cid()
+			if b.Cid().Type() != want.Type() || !bytes.Equal(b.Cid().Hash(), want.Hash()) {
+				return fmt.Errorf("block %s stored as %s", want, b.Cid())
+			}
+		}
+	}
+	return nil
+}
+
+// BlockStat returns the size of the block c.
+func (h *HttpClient) BlockStat(ctx context.Context, c cid.Cid) (*BlockStat, error) {
+	var out struct {
+		Key  string
+		Size int
+	}
+	if err := h.Request("block/stat", c.String()).Exec(ctx, &out); err != nil {
+		return nil, err
+	}
+
+	key, err := cid.Decode(out.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockStat{Cid: key, Size: out.Size}, nil
+}
+
+// BlockRm removes the given blocks from the node. Failures on individual
+// blocks are joined into the returned error.
+func (h *HttpClient) BlockRm(ctx context.Context, cids []cid.Cid, opts ...options.BlockRmOption) error {
+	settings, err := options.BlockRmOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	args := make([]string, 0, len(cids))
+	for _, c := range cids {
+		args = append(args, c.String())
+	}
+
+	resp, err := h.Request("block/rm", args...).
+		Option("force", settings.Force).
+		Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	var errs []error
+	dec := json.NewDecoder(resp.Output)
+	for {
+		var out struct {
+			Hash  string
+			Error string
+		}
+		if err := dec.Decode(&out); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if out.Error != "" {
+			errs = append(errs, fmt.Errorf("block %s: %s", out.Hash, out.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
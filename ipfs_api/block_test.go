@@ -0,0 +1,152 @@
+package ipfs_api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/urchinfs/go-urchin2-sdk/car"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+func TestBlockPut(t *testing.T) {
+	c, s := newTestClient(t)
+	ctx := context.Background()
+	data := [][]byte{[]byte("first"), []byte("second"), []byte("third")}
+
+	stored, err := c.BlockPut(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Requests("block/put"); n != 1 {
+		t.Errorf("%d requests, want all blocks in one", n)
+	}
+	if len(stored) != len(data) {
+		t.Fatalf("%d blocks stored, want %d", len(stored), len(data))
+	}
+	for i, b := range stored {
+		if !bytes.Equal(b.RawData(), data[i]) || b.Cid().Type() != cid.Raw || b.Cid().Version() != 1 {
+			t.Errorf("block %d = %s %q", i, b.Cid(), b.RawData())
+		}
+		stat, err := c.BlockStat(ctx, b.Cid())
+		if err != nil || stat.Size != len(data[i]) {
+			t.Errorf("stat %s = %+v, %v", b.Cid(), stat, err)
+		}
+	}
+
+	sha512, err := c.BlockPut(ctx, data[:1], options.Block.Codec("dag-cbor"), options.Block.Hash("sha2-512", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := sha512[0].Cid().Prefix(); p.Codec != cid.DagCBOR || p.MhType != mh.SHA2_512 {
+		t.Errorf("cid = %s, want a dag-cbor sha2-512 one", sha512[0].Cid())
+	}
+}
+
+func TestBlockPutBlocks(t *testing.T) {
+	c, s := newTestClient(t)
+	ctx := context.Background()
+
+	sum := func(prefix cid.Prefix, data string) blocks.Block {
+		id, err := prefix.Sum([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := blocks.NewBlockWithCid([]byte(data), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	raw := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: mh.SHA2_256, MhLength: -1}
+	blks := []blocks.Block{
+		blocks.NewBlock([]byte("dag-pb v0")),
+		sum(raw, "raw 1"),
+		sum(raw, "raw 2"),
+		sum(cid.Prefix{Version: 1, Codec: cid.Raw, MhType: mh.SHA2_512, MhLength: -1}, "sha2-512"),
+	}
+	if err := c.BlockPutBlocks(ctx, blks); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Requests("block/put"); n != 3 {
+		t.Errorf("%d requests, want one per codec and hash", n)
+	}
+	for _, b := range blks {
+		got, err := c.Block(ctx, b.Cid())
+		if err != nil || !bytes.Equal(got.RawData(), b.RawData()) {
+			t.Errorf("block %s = %v, %v", b.Cid(), got, err)
+		}
+	}
+}
+
+func TestBlockRejectsTampered(t *testing.T) {
+	c, s := newTestClient(t)
+	ctx := context.Background()
+	stored, err := c.BlockPut(ctx, [][]byte{[]byte("genuine")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := stored[0].Cid()
+
+	bs := s.Blockstore()
+	if err := bs.DeleteBlock(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	tampered, _ := blocks.NewBlockWithCid([]byte("tampered"), id)
+	if err := bs.Put(ctx, tampered); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Block(ctx, id)
+	var verr *car.VerificationError
+	if !errors.As(err, &verr) || !errors.Is(err, car.ErrHashMismatch) || !verr.Cid.Equals(id) {
+		t.Fatalf("block = %v, want a hash mismatch for %s", err, id)
+	}
+
+	// BlockGet leaves the check to the caller.
+	r, err := c.BlockGet(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil || buf.String() != "tampered" {
+		t.Errorf("block/get = %q, %v", buf.String(), err)
+	}
+}
+
+func TestBlockRm(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	stored, err := c.BlockPut(ctx, [][]byte{[]byte("loose"), []byte("pinned")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loose, pinned := stored[0].Cid(), stored[1].Cid()
+	if _, err := c.PinAdd(ctx, pinned.String()); err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.BlockRm(ctx, []cid.Cid{loose, pinned})
+	if err == nil || !strings.Contains(err.Error(), pinned.String()) || strings.Contains(err.Error(), loose.String()) {
+		t.Fatalf("rm = %v, want only %s refused", err, pinned)
+	}
+	if _, err := c.BlockStat(ctx, loose); !errors.Is(err, ErrNotFound) {
+		t.Errorf("stat of removed block = %v, want ErrNotFound", err)
+	}
+	if _, err := c.BlockStat(ctx, pinned); err != nil {
+		t.Errorf("pinned block removed: %v", err)
+	}
+
+	if err := c.BlockRm(ctx, []cid.Cid{loose}); err == nil {
+		t.Error("removing a missing block succeeded")
+	}
+	if err := c.BlockRm(ctx, []cid.Cid{loose}, options.Block.Force(true)); err != nil {
+		t.Errorf("forced rm of a missing block = %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package ipfs_api
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// maxResumes is the number of times in a row a ranged read is resumed after
+// the stream broke without delivering any data.
+const maxResumes = 3
+
+// CatRange streams length bytes of the file at path starting at offset. A
+// negative length reads up to the end of the file.
+func (h *HttpClient) CatRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	rb := h.Request("cat", path).Option("offset", offset)
+	if length >= 0 {
+		rb.Option("length", length)
+	}
+
+	resp, err := rb.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Output, nil
+}
+
+// CatReader opens the file at path for random access. Reads are served from
+// a buffer filled with ranged requests of options.Download.ReadAhead bytes;
+// a request broken halfway is resumed at the first missing byte.
+func (h *HttpClient) CatReader(ctx context.Context, path string, opts ...options.DownloadOption) (io.ReadSeekCloser, error) {
+	return newCatReader(ctx, h, path, opts...)
+}
+
+// rangeSource serves ranged reads, from a single node or from a pool.
+type rangeSource interface {
+	CatRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error)
+	FilesStat(ctx context.Context, path string) (*MfsStat, error)
+}
+
+type catReader struct {
+	ctx   context.Context
+	src   rangeSource
+	path  string
+	chunk int
+
+	off    int64
+	buf    []byte
+	bufOff int64
+	// size is the file size, -1 until known.
+	size   int64
+	closed bool
+}
+
+func newCatReader(ctx context.Context, src rangeSource, path string, opts ...options.DownloadOption) (*catReader, error) {
+	settings, err := options.DownloadOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &catReader{
+		ctx:   ctx,
+		src:   src,
+		path:  path,
+		chunk: settings.ReadAhead,
+		size:  -1,
+	}, nil
+}
+
+func (r *catReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, errors.New("read on closed reader")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.size >= 0 && r.off >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.off < r.bufOff || r.off >= r.bufOff+int64(len(r.buf)) {
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+		if len(r.buf) == 0 {
+			return 0, io.EOF
+		}
+	}
+
+	n := copy(p, r.buf[r.off-r.bufOff:])
+	r.off += int64(n)
+	return n, nil
+}
+
+// fill replaces the buffer with the chunk starting at the current offset.
+func (r *catReader) fill() error {
+	if cap(r.buf) < r.chunk {
+		r.buf = make([]byte, 0, r.chunk)
+	}
+	r.buf = r.buf[:0]
+	r.bufOff = r.off
+
+	failures := 0
+	for len(r.buf) < r.chunk {
+		start := r.bufOff + int64(len(r.buf))
+		rc, err := r.src.CatRange(r.ctx, r.path, start, int64(r.chunk-len(r.buf)))
+		if err != nil {
+			return err
+		}
+		n, err := readFull(rc, r.buf[len(r.buf):r.chunk])
+		rc.Close()
+		r.buf = r.buf[:len(r.buf)+n]
+
+		switch {
+		case err == nil:
+		case err == io.EOF:
+			// The node sent everything up to the end of the file.
+			r.size = r.bufOff + int64(len(r.buf))
+			return nil
+		case r.ctx.Err() != nil:
+			return r.ctx.Err()
+		case isPermanent(err):
+			return err
+		default:
+			if n > 0 {
+				failures = 0
+			} else {
+				failures++
+			}
+			if failures >= maxResumes {
+				return err
+			}
+			log.Warnf("cat %s broke at offset %d, resuming: %v", r.path, start+int64(n), err)
+		}
+	}
+	return nil
+}
+
+// readFull is io.ReadFull without turning an early io.EOF into
+// io.ErrUnexpectedEOF, which a truncated HTTP body also returns.
+func readFull(r io.Reader, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := r.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (r *catReader) Seek(offset int64, whence int) (int64, error) {
+	if r.closed {
+		return 0, errors.New("seek on closed reader")
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		if r.size < 0 {
+			stat, err := r.src.FilesStat(r.ctx, ipfsPath(r.path))
+			if err != nil {
+				return 0, err
+			}
+			r.size = int64(stat.Size)
+		}
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.off = offset
+	return offset, nil
+}
+
+func (r *catReader) Close() error {
+	r.closed = true
+	r.buf = nil
+	return nil
+}
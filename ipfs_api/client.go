@@ -5,20 +5,27 @@ import (
 	"encoding/json"
 	"fmt"
 	logging "github.com/ipfs/go-log"
+	caopts "github.com/urchinfs/go-urchin2-sdk/cid/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
 	"github.com/urchinfs/go-urchin2-sdk/utils"
 	"io"
 	"net"
 	gohttp "net/http"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/ipfs/boxo/files"
 	"github.com/ipfs/boxo/tar"
+	"github.com/ipfs/go-cid"
 	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
+	mh "github.com/multiformats/go-multihash"
 )
 
 const (
@@ -33,80 +40,145 @@ type HttpClient struct {
 
 	versionMu sync.Mutex
 	version   *semver.Version
+
+	retry          *RetryPolicy
+	requestTimeout time.Duration
+
+	headers  map[string]string
+	username string
+	password string
+	tokens   options.TokenSource
 }
 
-func NewClient(url string) *HttpClient {
-	c := &gohttp.Client{
-		Transport: &gohttp.Transport{
-			Proxy:             gohttp.ProxyFromEnvironment,
-			DisableKeepAlives: true,
-		},
+// NewClient creates a client for the Kubo RPC API at url, which may be a
+// host:port pair, an http(s) URL or a multiaddr such as /ip4/.../tcp/5001 or
+// /unix/path/to/api.sock.
+func NewClient(url string, opts ...options.ClientOption) (*HttpClient, error) {
+	settings, err := options.ClientOptions(opts...)
+	if err != nil {
+		return nil, err
 	}
 
 	var client HttpClient
 	client.url = url
-	client.httpCli = *c
-	client.httpCli.CheckRedirect = func(_ *gohttp.Request, _ []*gohttp.Request) error {
-		return fmt.Errorf("unexpected redirect")
+	client.retry = DefaultRetryPolicy()
+	client.requestTimeout = settings.RequestTimeout
+	client.headers = settings.Headers
+	client.username = settings.Username
+	client.password = settings.Password
+	client.tokens = settings.TokenSource
+
+	dialer := &net.Dialer{
+		Timeout:   settings.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	ownTransport := settings.HTTPClient == nil
+	if ownTransport {
+		client.httpCli = gohttp.Client{
+			Transport: &gohttp.Transport{
+				Proxy:                 settings.Proxy,
+				DialContext:           dialer.DialContext,
+				DisableKeepAlives:     !settings.KeepAlive,
+				MaxIdleConns:          settings.MaxIdleConns,
+				MaxIdleConnsPerHost:   settings.MaxIdleConnsPerHost,
+				IdleConnTimeout:       settings.IdleConnTimeout,
+				ResponseHeaderTimeout: settings.ResponseHeaderTimeout,
+				TLSHandshakeTimeout:   10 * time.Second,
+			},
+		}
+	} else {
+		client.httpCli = *settings.HTTPClient
+	}
+	if client.httpCli.CheckRedirect == nil {
+		client.httpCli.CheckRedirect = func(_ *gohttp.Request, _ []*gohttp.Request) error {
+			return fmt.Errorf("unexpected redirect")
+		}
+	}
+
+	if settings.TLSConfig != nil {
+		tpt, err := client.cloneTransport()
+		if err != nil {
+			return nil, err
+		}
+		tpt.TLSClientConfig = settings.TLSConfig
+		client.httpCli.Transport = tpt
 	}
 
+	secure := settings.TLSConfig != nil
 	maddr, err := ma.NewMultiaddr(url)
 	if err != nil {
-		return &client
+		if secure && !strings.HasPrefix(url, "http") {
+			client.url = "https://" + url
+		}
+		return &client, nil
 	}
 
 	network, host, err := manet.DialArgs(maddr)
 	if err != nil {
-		return &client
+		return &client, nil
 	}
 
 	if network == "unix" {
 		client.url = network
 
-		var tptCopy *gohttp.Transport
-		if tpt, ok := client.httpCli.Transport.(*gohttp.Transport); ok && tpt.DialContext == nil {
-			tptCopy = tpt.Clone()
-		} else if client.httpCli.Transport == nil {
-			tptCopy = &gohttp.Transport{
-				Proxy:             gohttp.ProxyFromEnvironment,
-				DisableKeepAlives: true,
-			}
-		} else {
-			return &client
+		tptCopy, err := client.cloneTransport()
+		if err != nil || (!ownTransport && tptCopy.DialContext != nil) {
+			return &client, nil
 		}
 
-		tptCopy.DialContext = func(_ context.Context, _, _ string) (net.Conn, error) {
-			return net.Dial("unix", host)
+		tptCopy.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", host)
 		}
 		client.httpCli.Transport = tptCopy
 	} else {
 		client.url = host
+		if secure || hasProtocol(maddr, ma.P_HTTPS) || hasProtocol(maddr, ma.P_TLS) {
+			client.url = "https://" + host
+		}
 	}
 
-	return &client
+	return &client, nil
+}
+
+// cloneTransport returns a copy of the client's transport that can be
+// modified without affecting a transport supplied by the caller.
+func (h *HttpClient) cloneTransport() (*gohttp.Transport, error) {
+	switch tpt := h.httpCli.Transport.(type) {
+	case nil:
+		return gohttp.DefaultTransport.(*gohttp.Transport).Clone(), nil
+	case *gohttp.Transport:
+		return tpt.Clone(), nil
+	default:
+		return nil, fmt.Errorf("transport of type %T can not be configured", tpt)
+	}
+}
+
+func hasProtocol(maddr ma.Multiaddr, code int) bool {
+	_, err := maddr.ValueForProtocol(code)
+	return err == nil
 }
 
 var encodedAbsolutePathVersion = semver.MustParse("0.28.0-dev")
 
-func (h *HttpClient) Version() (string, string, error) {
+func (h *HttpClient) Version(ctx context.Context) (string, string, error) {
 	ver := struct {
 		Version string
 		Commit  string
 	}{}
 
-	if err := h.Request("version").Exec(context.Background(), &ver); err != nil {
+	if err := h.Request("version").Exec(ctx, &ver); err != nil {
 		return "", "", err
 	}
 
 	return ver.Version, ver.Commit, nil
 }
 
-func (h *HttpClient) loadRemoteVersion() (*semver.Version, error) {
+func (h *HttpClient) loadRemoteVersion(ctx context.Context) (*semver.Version, error) {
 	h.versionMu.Lock()
 	defer h.versionMu.Unlock()
 
 	if h.version == nil {
-		version, _, err := h.Version()
+		version, _, err := h.Version(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -122,8 +194,8 @@ func (h *HttpClient) loadRemoteVersion() (*semver.Version, error) {
 	return h.version, nil
 }
 
-func (h *HttpClient) newMultiFileReader(dir files.Directory) (*files.MultiFileReader, error) {
-	version, err := h.loadRemoteVersion()
+func (h *HttpClient) newMultiFileReader(ctx context.Context, dir files.Directory) (*files.MultiFileReader, error) {
+	version, err := h.loadRemoteVersion(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +207,12 @@ func (h *HttpClient) SetTimeout(d time.Duration) {
 	h.httpCli.Timeout = d
 }
 
+// SetRetryPolicy replaces the policy used to retry idempotent commands. A nil
+// policy disables retries.
+func (h *HttpClient) SetRetryPolicy(policy *RetryPolicy) {
+	h.retry = policy
+}
+
 func (h *HttpClient) Request(command string, args ...string) *RequestBuilder {
 	return &RequestBuilder{
 		command: command,
@@ -143,8 +221,18 @@ func (h *HttpClient) Request(command string, args ...string) *RequestBuilder {
 	}
 }
 
-func (h *HttpClient) Cat(path string) (io.ReadCloser, error) {
-	resp, err := h.Request("cat", path).Send(context.Background())
+func (h *HttpClient) Cat(ctx context.Context, path string, opts ...options.DownloadOption) (io.ReadCloser, error) {
+	settings, err := options.DownloadOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if settings.Events != nil {
+		total = h.expectedFileSize(ctx, path)
+	}
+
+	resp, err := h.Request("cat", path).Send(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -152,6 +240,10 @@ func (h *HttpClient) Cat(path string) (io.ReadCloser, error) {
 		return nil, resp.Error
 	}
 
+	if settings.Events != nil {
+		progress := newDownloadProgress(ctx, settings.Events, path, total)
+		return &progressReader{r: resp.Output, p: progress, final: true}, nil
+	}
 	return resp.Output, nil
 }
 
@@ -167,9 +259,9 @@ type LsObject struct {
 	LsLink
 }
 
-func (h *HttpClient) List(path string) ([]*LsLink, error) {
+func (h *HttpClient) List(ctx context.Context, path string) ([]*LsLink, error) {
 	var out struct{ Objects []LsObject }
-	err := h.Request("ls", path).Exec(context.Background(), &out)
+	err := h.Request("ls", path).Exec(ctx, &out)
 	if err != nil {
 		return nil, err
 	}
@@ -179,7 +271,23 @@ func (h *HttpClient) List(path string) ([]*LsLink, error) {
 	return out.Objects[0].Links, nil
 }
 
-func (h *HttpClient) Get(hash, outDir string) error {
+// Downloader is the download interface shared by HttpClient, ClientPool and
+// gateway.Client, so callers can switch transports.
+type Downloader interface {
+	Get(ctx context.Context, hash, outDir string, opts ...options.DownloadOption) error
+}
+
+var (
+	_ Downloader = (*HttpClient)(nil)
+	_ Downloader = (*ClientPool)(nil)
+)
+
+func (h *HttpClient) Get(ctx context.Context, hash, outDir string, opts ...options.DownloadOption) error {
+	settings, err := options.DownloadOptions(opts...)
+	if err != nil {
+		return err
+	}
+
 	stat, err := os.Stat(outDir)
 	if err != nil {
 		return err
@@ -187,8 +295,25 @@ func (h *HttpClient) Get(hash, outDir string) error {
 	if stat.IsDir() {
 		outDir = path.Join(outDir, hash)
 	}
+	if settings.Resume {
+		err = h.getResumable(ctx, hash, outDir, settings)
+	} else {
+		err = h.getTar(ctx, hash, outDir, settings)
+	}
+	if err != nil || !settings.Verify {
+		return err
+	}
+	return h.verifyTree(ctx, hash, outDir, settings.VerifyAdd)
+}
+
+// getTar downloads hash as a single tar stream extracted into outDir.
+func (h *HttpClient) getTar(ctx context.Context, hash, outDir string, settings *options.DownloadSettings) error {
+	var total int64
+	if settings.Events != nil {
+		total = h.expectedFileSize(ctx, hash)
+	}
 
-	resp, err := h.Request("get", hash).Option("create", true).Send(context.Background())
+	resp, err := h.Request("get", hash).Option("create", true).Send(ctx)
 	if err != nil {
 		return err
 	}
@@ -199,7 +324,29 @@ func (h *HttpClient) Get(hash, outDir string) error {
 	}
 
 	extractor := &tar.Extractor{Path: outDir}
-	return extractor.Extract(resp.Output)
+	output := io.Reader(&ctxReader{ctx: ctx, r: resp.Output})
+	if settings.Events == nil {
+		return extractor.Extract(output)
+	}
+
+	// Follow the archive in a second reader to learn which entry is being
+	// extracted; the extractor itself gives no visibility.
+	progress := newDownloadProgress(ctx, settings.Events, hash, total)
+	pr, pw := io.Pipe()
+	watched := make(chan error, 1)
+	go func() {
+		watched <- progress.watchTar(pr)
+	}()
+
+	err = extractor.Extract(io.TeeReader(output, pw))
+	_ = pw.CloseWithError(err)
+	if werr := <-watched; werr != nil {
+		log.Debugf("follow tar stream of %s err:%v", hash, werr)
+	}
+	if err != nil {
+		return err
+	}
+	return progress.done()
 }
 
 type SwarmStreamInfo struct {
@@ -236,133 +383,301 @@ func (h *HttpClient) SwarmConnect(ctx context.Context, addr ...string) error {
 	return err
 }
 
-type object struct {
-	Hash string
+type addOutput struct {
+	Name  string
+	Hash  string
+	Bytes int64
+	Size  string
 }
 
-type AddOpts = func(*RequestBuilder) error
+// AddEntry is a file or directory stored by an add command. Size is the
+// cumulative size of its DAG.
+type AddEntry struct {
+	Name string
+	Cid  cid.Cid
+	Size uint64
+}
+
+// AddResult describes a finished upload. Root is the CID of the uploaded
+// file or directory itself; Wrapper is the CID of the directory wrapping it,
+// or cid.Undef when wrapping was disabled. Entries lists every file and
+// directory stored, in the order the node reported them. Bytes is the size of
+// the request body sent and Node the address of the node that handled it.
+type AddResult struct {
+	Wrapper cid.Cid
+	Root    cid.Cid
+	Entries []AddEntry
+	Bytes   int64
+	Node    string
+}
+
+// AddOpts are shared with the offline cid package, so content added to a
+// node and a CID computed locally use the same settings.
+type AddOpts = caopts.UnixfsAddOption
 
 func Pin(enabled bool) AddOpts {
-	return func(rb *RequestBuilder) error {
-		rb.Option("pin", enabled)
-		return nil
-	}
+	return caopts.Unixfs.Pin(enabled)
 }
 
-func (h *HttpClient) Add(inputFile string, options ...AddOpts) (string, error) {
+// Add uploads a single file, wrapped in a directory unless
+// options.Unixfs.Wrap(false) is given. When an Events channel is set in
+// options, an *AddEvent is sent to it for every progress update and finished
+// entry, the last one being the wrapping directory with an empty Name. The
+// channel is not closed.
+func (h *HttpClient) Add(ctx context.Context, inputFile string, options ...AddOpts) (*AddResult, error) {
 	stat, err := os.Stat(inputFile)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if stat.IsDir() {
-		return "", utils.ErrNotFile
+		return nil, utils.ErrNotFile
 	}
 
-	wrapDataDir, err := utils.WarpPath(inputFile)
+	rb := h.Request("add")
+	result, err := h.add(ctx, rb, h.pathBody(ctx, inputFile), true, options...)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	fileReader, err := h.newMultiFileReader(wrapDataDir)
-	if err != nil {
-		log.Errorf("new multi file reader err:%v", err)
-		return "", err
-	}
+	log.Debugf("received warp hash: %s", result.Wrapper)
+	return result, nil
+}
+
+// pathBody returns a body builder that walks the path again on every call, so
+// an upload from disk can be retried from the start.
+func (h *HttpClient) pathBody(ctx context.Context, path string) func() (io.Reader, error) {
+	return func() (io.Reader, error) {
+		wrapDataDir, err := utils.WarpPath(path)
+		if err != nil {
+			return nil, err
+		}
 
-	rb := h.Request("add").Option("wrap-with-directory", true)
-	for _, option := range options {
-		option(rb)
+		fileReader, err := h.newMultiFileReader(ctx, wrapDataDir)
+		if err != nil {
+			log.Errorf("new multi file reader err:%v", err)
+			return nil, err
+		}
+		return fileReader, nil
 	}
+}
+
+func (h *HttpClient) AddNoPin(ctx context.Context, inputFile string) (*AddResult, error) {
+	return h.Add(ctx, inputFile, Pin(false))
+}
 
-	resp, err := rb.Body(fileReader).Send(context.Background())
+// AddDir uploads a directory recursively. Progress is reported the same way
+// as for Add.
+func (h *HttpClient) AddDir(ctx context.Context, dir string, options ...AddOpts) (*AddResult, error) {
+	stat, err := os.Stat(dir)
 	if err != nil {
-		log.Errorf("send http err:%v", err)
-		return "", err
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, utils.ErrNotDir
 	}
-	defer resp.Close()
 
-	if resp.Error != nil {
-		return "", resp.Error
+	rb := h.Request("add").Option("recursive", true)
+	return h.add(ctx, rb, h.pathBody(ctx, dir), true, options...)
+}
+
+// AddReader uploads the data read from r as a file called name. The upload
+// can only be retried when r is an io.Seeker.
+func (h *HttpClient) AddReader(ctx context.Context, name string, r io.Reader, options ...AddOpts) (*AddResult, error) {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return h.AddNode(ctx, name, files.NewReaderFile(r), options...)
 	}
 
-	dec := json.NewDecoder(resp.Output)
-	var final string
-	for {
-		var out object
-		err = dec.Decode(&out)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return "", err
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	body := func() (io.Reader, error) {
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
 		}
-		final = out.Hash
+		// Keep r open across attempts, the multipart reader closes each part.
+		return h.nodeBody(ctx, name, files.NewReaderFile(io.NopCloser(r)))
 	}
+	return h.add(ctx, h.Request("add"), body, true, options...)
+}
 
-	if final == "" {
-		return "", utils.ErrNotReceiveRet
+// AddBytes uploads data as a file called name.
+func (h *HttpClient) AddBytes(ctx context.Context, name string, data []byte, options ...AddOpts) (*AddResult, error) {
+	body := func() (io.Reader, error) {
+		return h.nodeBody(ctx, name, files.NewBytesFile(data))
 	}
+	return h.add(ctx, h.Request("add"), body, true, options...)
+}
 
-	log.Debugf("received warp hash: %s", final)
-	return final, nil
+// AddNode uploads an in-memory file tree, such as one built with
+// files.NewMapDirectory, under name. A node can only be read once, so the
+// upload is not retried.
+func (h *HttpClient) AddNode(ctx context.Context, name string, node files.Node, options ...AddOpts) (*AddResult, error) {
+	rb := h.Request("add")
+	if _, ok := node.(files.Directory); ok {
+		rb.Option("recursive", true)
+	}
+
+	body := func() (io.Reader, error) {
+		return h.nodeBody(ctx, name, node)
+	}
+	return h.add(ctx, rb, body, false, options...)
 }
 
-func (h *HttpClient) AddNoPin(inputFile string) (string, error) {
-	return h.Add(inputFile, Pin(false))
+// nodeBody wraps node in a directory entry called name, the same way
+// utils.WarpPath does for files on disk.
+func (h *HttpClient) nodeBody(ctx context.Context, name string, node files.Node) (io.Reader, error) {
+	dir := files.NewSliceDirectory([]files.DirEntry{files.FileEntry(name, node)})
+	return h.newMultiFileReader(ctx, dir)
 }
 
-func (h *HttpClient) AddDir(dir string, options ...AddOpts) (string, error) {
-	stat, err := os.Stat(dir)
-	if err != nil {
-		return "", err
+// addRequestOptions translates add settings into options of the add command,
+// so the node builds the DAG exactly like cid.GetCid does with the same
+// settings.
+func addRequestOptions(rb *RequestBuilder, settings *caopts.UnixfsAddSettings) error {
+	hash, ok := mh.Codes[settings.MhType]
+	if !ok {
+		return fmt.Errorf("unknown multihash type: %d", settings.MhType)
 	}
-	if !stat.IsDir() {
-		return "", utils.ErrNotDir
+
+	rb.Option("pin", settings.Pin).
+		Option("wrap-with-directory", settings.Wrap).
+		Option("cid-version", settings.CidVersion).
+		Option("hash", hash).
+		Option("raw-leaves", settings.RawLeaves).
+		Option("chunker", settings.Chunker).
+		Option("trickle", settings.Layout == caopts.TrickleLayout).
+		Option("inline", settings.Inline).
+		Option("inline-limit", settings.InlineLimit).
+		Option("only-hash", settings.OnlyHash)
+
+	if settings.NoCopy {
+		rb.Option("nocopy", true)
 	}
+	if settings.FsCache {
+		rb.Option("fscache", true)
+	}
+	if settings.ToFiles != "" {
+		rb.Option("to-files", settings.ToFiles)
+	}
+	if settings.PreserveMode {
+		rb.Option("preserve-mode", true)
+	}
+	if settings.PreserveMtime {
+		rb.Option("preserve-mtime", true)
+	}
+	if settings.Events != nil {
+		rb.Option("progress", true)
+	}
+	return nil
+}
 
-	wrapDataDir, err := utils.WarpPath(dir)
+// add sends an add request with the body built by body and collects the
+// entries reported by the node.
+func (h *HttpClient) add(ctx context.Context, rb *RequestBuilder, body func() (io.Reader, error), rewindable bool, options ...AddOpts) (*AddResult, error) {
+	settings, _, err := caopts.UnixfsAddOptions(options...)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	reader, err := h.newMultiFileReader(wrapDataDir)
-	if err != nil {
-		return "", err
+	if err := addRequestOptions(rb, settings); err != nil {
+		return nil, err
 	}
 
-	rb := h.Request("add").Option("recursive", true).Option("wrap-with-directory", true)
-	for _, option := range options {
-		option(rb)
+	var sent atomic.Int64
+	counted := func() (io.Reader, error) {
+		r, err := body()
+		if err != nil {
+			return nil, err
+		}
+		sent.Store(0)
+		return &countingReader{r: r, n: &sent}, nil
+	}
+	if rewindable {
+		rb.BodyFunc(counted)
+	} else {
+		r, err := counted()
+		if err != nil {
+			return nil, err
+		}
+		rb.Body(r)
 	}
 
-	resp, err := rb.Body(reader).Send(context.Background())
+	resp, err := rb.Send(ctx)
 	if err != nil {
-		return "", err
+		log.Errorf("send http err:%v", err)
+		return nil, err
 	}
 	defer resp.Close()
 
 	if resp.Error != nil {
-		return "", resp.Error
+		return nil, resp.Error
 	}
 
+	result := &AddResult{Node: h.url}
 	dec := json.NewDecoder(resp.Output)
-	var final string
 	for {
-		var out object
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var out addOutput
 		err = dec.Decode(&out)
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return "", err
+			return nil, err
+		}
+
+		var c cid.Cid
+		if out.Hash != "" {
+			if c, err = cid.Decode(out.Hash); err != nil {
+				return nil, err
+			}
+		}
+
+		if settings.Events != nil {
+			ev := &AddEvent{
+				Name:  out.Name,
+				CID:   c,
+				Bytes: out.Bytes,
+				Size:  out.Size,
+				Sent:  sent.Load(),
+			}
+
+			select {
+			case settings.Events <- ev:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		switch {
+		case !c.Defined():
+		case out.Name == "" && settings.Wrap:
+			result.Wrapper = c
+		default:
+			size, _ := strconv.ParseUint(out.Size, 10, 64)
+			result.Entries = append(result.Entries, AddEntry{
+				Name: out.Name,
+				Cid:  c,
+				Size: size,
+			})
+			// Top-level entries have no parent in their name; the node
+			// reports a directory after its children, so the last one wins.
+			if !strings.Contains(out.Name, "/") {
+				result.Root = c
+			}
 		}
-		final = out.Hash
 	}
+	result.Bytes = sent.Load()
 
-	if final == "" {
+	if !result.Root.Defined() {
 		log.Warnf("no results received from ipfs peer")
-		return "", utils.ErrNotReceiveRet
+		return nil, utils.ErrNotReceiveRet
 	}
 
-	return final, nil
+	return result, nil
 }
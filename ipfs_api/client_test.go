@@ -0,0 +1,387 @@
+package ipfs_api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/go-cid"
+	"github.com/urchinfs/go-urchin2-sdk/car"
+	sdkcid "github.com/urchinfs/go-urchin2-sdk/cid"
+	caopts "github.com/urchinfs/go-urchin2-sdk/cid/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/testserver"
+)
+
+// fastRetry is the default policy without the waits.
+func fastRetry() *RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = 5 * time.Millisecond
+	return p
+}
+
+func newTestClient(t *testing.T, opts ...options.ClientOption) (*HttpClient, *testserver.Server) {
+	t.Helper()
+	s := testserver.New()
+	t.Cleanup(s.Close)
+
+	c, err := NewClient(s.URL(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRetryPolicy(fastRetry())
+	return c, s
+}
+
+func randomBytes(seed int64, n int) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(b)
+	return b
+}
+
+// testTree is a small directory with hidden files at every level and a file
+// spanning several chunks.
+func testTree() files.Node {
+	return files.NewMapDirectory(map[string]files.Node{
+		".hidden": files.NewBytesFile([]byte("hidden")),
+		"a.txt":   files.NewBytesFile([]byte("hello world")),
+		"sub": files.NewMapDirectory(map[string]files.Node{
+			".config": files.NewBytesFile([]byte("config")),
+			"b.bin":   files.NewBytesFile(randomBytes(1, 600000)),
+		}),
+	})
+}
+
+func addTree(t *testing.T, c *HttpClient, opts ...AddOpts) cid.Cid {
+	t.Helper()
+	res, err := c.AddNode(context.Background(), "tree", testTree(), append(opts, caopts.Unixfs.Wrap(false))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.Root
+}
+
+func TestAddBytes(t *testing.T) {
+	c, s := newTestClient(t)
+
+	res, err := c.AddBytes(context.Background(), "hello.txt", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "QmWfVY9y3xjsixTgbd9AorQxH7VtMpzfx2HaWtsoUYecaX"; res.Root.String() != want {
+		t.Errorf("root = %s, want %s", res.Root, want)
+	}
+	if !res.Wrapper.Defined() {
+		t.Error("wrapping directory missing")
+	}
+	if has, _ := s.Blockstore().Has(context.Background(), res.Root); !has {
+		t.Error("node does not hold the added file")
+	}
+}
+
+func TestGetCidMatchesAdd(t *testing.T) {
+	c, _ := newTestClient(t)
+	dir := filepath.Join(t.TempDir(), "tree")
+	if err := files.WriteTo(testTree(), dir); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{dir, filepath.Join(dir, "sub", "b.bin")}
+
+	tests := []struct {
+		name string
+		opts []AddOpts
+	}{
+		{"default", nil},
+		{"no wrap", []AddOpts{caopts.Unixfs.Wrap(false)}},
+		{"cid v1", []AddOpts{caopts.Unixfs.CidVersion(1)}},
+		{"cid v1 without raw leaves", []AddOpts{caopts.Unixfs.CidVersion(1), caopts.Unixfs.RawLeaves(false)}},
+		{"raw leaves", []AddOpts{caopts.Unixfs.RawLeaves(true)}},
+		{"trickle", []AddOpts{caopts.Unixfs.Trickle(true)}},
+		{"all", []AddOpts{caopts.Unixfs.Trickle(true), caopts.Unixfs.RawLeaves(true),
+			caopts.Unixfs.CidVersion(1), caopts.Unixfs.Wrap(false)}},
+	}
+	for _, tt := range tests {
+		for _, p := range paths {
+			t.Run(tt.name+"/"+filepath.Base(p), func(t *testing.T) {
+				want, err := sdkcid.GetCid(p, tt.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// Upload what Add and AddDir read from disk.
+				filter, err := files.NewFilter("", nil, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				node, err := sdkcid.AppendFile(p, true, filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res, err := c.AddNode(context.Background(), filepath.Base(p), node, tt.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := res.Wrapper
+				if settings, _, _ := caopts.UnixfsAddOptions(tt.opts...); !settings.Wrap {
+					got = res.Root
+				}
+				if !got.Equals(want) {
+					t.Errorf("GetCid = %s, node added %s", want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestGetVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []AddOpts
+	}{
+		{"default", nil},
+		{"cidv1", []AddOpts{caopts.Unixfs.CidVersion(1)}},
+		{"trickle", []AddOpts{caopts.Unixfs.Layout(caopts.TrickleLayout), caopts.Unixfs.RawLeaves(true)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t)
+			root := addTree(t, c, tt.opts...)
+
+			for _, resume := range []bool{false, true} {
+				dir := t.TempDir()
+				err := c.Get(context.Background(), root.String(), dir,
+					options.Download.Verify(tt.opts...), options.Download.Resume(resume))
+				if err != nil {
+					t.Fatalf("resume %v: %v", resume, err)
+				}
+
+				got, err := os.ReadFile(filepath.Join(dir, root.String(), "sub", ".config"))
+				if err != nil || string(got) != "config" {
+					t.Errorf("resume %v: hidden file = %q, %v", resume, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyReportsBadFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(dir string) error
+		want   error
+	}{
+		{
+			name: "extra file",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "sub", "extra"), nil, 0644)
+			},
+			want: ErrExtraFile,
+		},
+		{
+			name: "missing hidden file",
+			change: func(dir string) error {
+				return os.Remove(filepath.Join(dir, ".hidden"))
+			},
+			want: ErrMissingFile,
+		},
+		{
+			name: "changed hidden file",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "sub", ".config"), []byte("changed"), 0644)
+			},
+			want: car.ErrHashMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t)
+			root := addTree(t, c)
+			ctx := context.Background()
+
+			dir := t.TempDir()
+			if err := c.Get(ctx, root.String(), dir); err != nil {
+				t.Fatal(err)
+			}
+			out := filepath.Join(dir, root.String())
+			if err := c.verifyTree(ctx, root.String(), out, nil); err != nil {
+				t.Fatalf("verify untouched download: %v", err)
+			}
+
+			if err := tt.change(out); err != nil {
+				t.Fatal(err)
+			}
+			err := c.verifyTree(ctx, root.String(), out, nil)
+			var verr *car.VerificationError
+			if !errors.As(err, &verr) || !errors.Is(err, tt.want) {
+				t.Fatalf("verify = %v, want a VerificationError for %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatReaderResume(t *testing.T) {
+	c, s := newTestClient(t)
+	ctx := context.Background()
+	data := randomBytes(2, 600000)
+	res, err := c.AddBytes(ctx, "big", data, caopts.Unixfs.Wrap(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Break the first two reads halfway.
+	s.Inject("cat", testserver.Fault{Truncate: true, TruncateAfter: 100000, Times: 2})
+	r, err := c.CatReader(ctx, res.Root.String(), options.Download.ReadAhead(256<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var got bytes.Buffer
+	if _, err := got.ReadFrom(r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Fatalf("read %d bytes, not the %d added", got.Len(), len(data))
+	}
+	if n := s.Requests("cat"); n < 5 {
+		t.Errorf("%d cat requests, want the 3 ranges plus 2 resumes", n)
+	}
+
+	if _, err := r.Seek(500000, 0); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	if _, err := r.Read(buf); err != nil || !bytes.Equal(buf, data[500000:500016]) {
+		t.Errorf("read after seek = %x, %v", buf, err)
+	}
+}
+
+func TestCatReaderNotFound(t *testing.T) {
+	c, s := newTestClient(t)
+	ctx := context.Background()
+	res, err := c.AddBytes(ctx, "missing", []byte("never stored"),
+		caopts.Unixfs.Wrap(false), caopts.Unixfs.HashOnly(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.CatReader(ctx, res.Root.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Read(make([]byte, 1)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("read = %v, want ErrNotFound", err)
+	}
+	if n := s.Requests("cat"); n > 1 {
+		t.Errorf("missing content requested %d times", n)
+	}
+}
+
+func TestGetResume(t *testing.T) {
+	c, s := newTestClient(t)
+	ctx := context.Background()
+	res, err := c.AddNode(ctx, "tree", files.NewMapDirectory(map[string]files.Node{
+		"big.bin": files.NewBytesFile(randomBytes(3, 600000)),
+		"sub": files.NewMapDirectory(map[string]files.Node{
+			".hidden.bin": files.NewBytesFile(randomBytes(4, 300000)),
+		}),
+	}), caopts.Unixfs.Wrap(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := res.Root.String()
+
+	// Both files break once and are continued where they stopped.
+	s.Inject("cat", testserver.Fault{Truncate: true, TruncateAfter: 200000, Times: 2})
+	dir := t.TempDir()
+	if err := c.Get(ctx, root, dir, options.Download.Resume(true), options.Download.Verify()); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Requests("cat"); n != 4 {
+		t.Errorf("%d cat requests, want 2 files plus 2 resumes", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, root, resumeStateFile)); !os.IsNotExist(err) {
+		t.Errorf("state file left behind: %v", err)
+	}
+
+	// Files already on disk are not downloaded again.
+	if err := c.Get(ctx, root, dir, options.Download.Resume(true), options.Download.Verify()); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Requests("cat"); n != 4 {
+		t.Errorf("second download sent %d more cat requests", n-4)
+	}
+}
+
+// failResumableGet starts a resumable Get of a single 600000 byte file that
+// breaks after 200000 bytes and then fails, leaving the partial file and the
+// state file behind.
+func failResumableGet(t *testing.T, c *HttpClient, s *testserver.Server, dir string) (root string, data []byte) {
+	t.Helper()
+	ctx := context.Background()
+	data = randomBytes(5, 600000)
+	res, err := c.AddNode(ctx, "tree", files.NewMapDirectory(map[string]files.Node{
+		"big.bin": files.NewBytesFile(data),
+	}), caopts.Unixfs.Wrap(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root = res.Root.String()
+
+	s.Inject("cat", testserver.Fault{Truncate: true, TruncateAfter: 200000, Times: 1})
+	s.Inject("cat", testserver.Fault{Status: http.StatusInternalServerError})
+	if err := c.Get(ctx, root, dir, options.Download.Resume(true)); err == nil {
+		t.Fatal("download succeeded despite the failing node")
+	}
+	s.ClearFaults()
+
+	parts, err := filepath.Glob(filepath.Join(dir, root, ".*.part"))
+	if err != nil || len(parts) != 1 {
+		t.Fatalf("partial files = %v, %v", parts, err)
+	}
+	return root, data
+}
+
+func TestVerifyIgnoresResumeLeftovers(t *testing.T) {
+	c, s := newTestClient(t)
+	dir := t.TempDir()
+	root, _ := failResumableGet(t, c, s, dir)
+
+	if err := c.Get(context.Background(), root, dir, options.Download.Verify()); err != nil {
+		t.Fatalf("verify next to a partial download: %v", err)
+	}
+}
+
+func TestGetResumeRestartsBadPart(t *testing.T) {
+	c, s := newTestClient(t)
+	dir := t.TempDir()
+	root, data := failResumableGet(t, c, s, dir)
+
+	parts, _ := filepath.Glob(filepath.Join(dir, root, ".*.part"))
+	if err := os.WriteFile(parts[0], make([]byte, 200000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	before := s.Requests("cat")
+	ctx := context.Background()
+	if err := c.Get(ctx, root, dir, options.Download.Resume(true), options.Download.Verify()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, root, "big.bin"))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("file = %d bytes, %v; want the added content", len(got), err)
+	}
+	if n := s.Requests("cat") - before; n != 2 {
+		t.Errorf("%d cat requests, want the resume and the download from the start", n)
+	}
+}
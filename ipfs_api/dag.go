@@ -1,9 +1,11 @@
 package ipfs_api
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
 	"io"
@@ -11,6 +13,14 @@ import (
 	"strings"
 
 	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipld/go-ipld-prime"
+	_ "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	_ "github.com/ipld/go-ipld-prime/codec/dagjson"
+	_ "github.com/ipld/go-ipld-prime/codec/raw"
+	"github.com/ipld/go-ipld-prime/multicodec"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/urchinfs/go-urchin2-sdk/car"
 )
 
 type DagImportRoot struct {
@@ -32,36 +42,218 @@ type DagImportOutput struct {
 	Stats *DagImportStats
 }
 
-func (h *HttpClient) DagGet(ref string, out interface{}) error {
-	return h.Request("dag/get", ref).Exec(context.Background(), out)
+// DagStatEvent reports the progress of DagStat: the blocks visited so far,
+// counted once per DAG they are in, and the size of the distinct ones.
+type DagStatEvent struct {
+	Blocks int
+	Size   uint64
 }
-func (h *HttpClient) DagImport(input string, silent, stats bool) (*DagImportOutput, error) {
-	iFd, err := os.Open(input)
+
+// DagStatEntry is the size of a single DAG.
+type DagStatEntry struct {
+	Cid       cid.Cid
+	Size      uint64
+	NumBlocks int64
+}
+
+// DagStatResult sums up the DAGs walked by DagStat. Blocks shared between
+// DAGs are only counted once in UniqueBlocks and TotalSize.
+type DagStatResult struct {
+	UniqueBlocks int
+	TotalSize    uint64
+	SharedSize   uint64
+	Ratio        float32
+	Dags         []DagStatEntry
+}
+
+// DagGet fetches the object at ref, a CID optionally followed by a path,
+// and decodes it with the codec chosen by options.Dag.OutputCodec.
+func (h *HttpClient) DagGet(ctx context.Context, ref string, opts ...options.DagGetOption) (ipld.Node, error) {
+	settings, err := options.DagGetOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	decode, err := multicodec.LookupDecoder(uint64(settings.OutputCodec))
 	if err != nil {
 		return nil, err
 	}
 
-	rc := io.ReadCloser(iFd)
-	return h.DagImportWithOpts(rc, options.Dag.Silent(silent), options.Dag.Stats(stats))
+	resp, err := h.Request("dag/get", ref).
+		Option("output-codec", settings.OutputCodec.String()).
+		Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := decode(nb, resp.Output); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
 }
 
-func (h *HttpClient) DagImportWithOpts(data interface{}, opts ...options.DagImportOption) (*DagImportOutput, error) {
-	cfg, err := options.DagImportOptions(opts...)
+// DagGetJSON decodes the dag-json form of the object at ref into out.
+func (h *HttpClient) DagGetJSON(ctx context.Context, ref string, out interface{}) error {
+	return h.Request("dag/get", ref).Exec(ctx, out)
+}
+
+// DagPut stores node on the node, encoded with options.Dag.StoreCodec, and
+// returns its CID.
+func (h *HttpClient) DagPut(ctx context.Context, node ipld.Node, opts ...options.DagPutOption) (cid.Cid, error) {
+	settings, err := options.DagPutOptions(opts...)
+	if err != nil {
+		return cid.Undef, err
+	}
+	encode, err := multicodec.LookupEncoder(uint64(settings.InputCodec))
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	var buf bytes.Buffer
+	if err := encode(node, &buf); err != nil {
+		return cid.Undef, err
+	}
+
+	resp, err := h.Request("dag/put").
+		Option("store-codec", settings.StoreCodec.String()).
+		Option("input-codec", settings.InputCodec.String()).
+		Option("hash", settings.Hash).
+		Option("pin", settings.Pin).
+		Option("allow-big-block", settings.AllowBigBlock).
+		BodyFunc(func() (io.Reader, error) {
+			return h.nodeBody(ctx, "", files.NewBytesFile(buf.Bytes()))
+		}).
+		Send(ctx)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	var out struct{ Cid cid.Cid }
+	if err := resp.Decode(&out); err != nil {
+		return cid.Undef, err
+	}
+	return out.Cid, nil
+}
+
+// DagStat walks the DAG under ref and counts its blocks and their total size.
+func (h *HttpClient) DagStat(ctx context.Context, ref string, opts ...options.DagStatOption) (*DagStatResult, error) {
+	settings, err := options.DagStatOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.Request("dag/stat", ref).
+		Option("progress", settings.Events != nil).
+		Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	// With progress the node streams a running summary after every block;
+	// only the last one, sent once the walk is over, is complete. Each
+	// summary is held back until the next one shows it was not the last.
+	type dagStat struct {
+		Cid       string
+		Size      uint64
+		NumBlocks int64
+	}
+	type summary struct {
+		UniqueBlocks int
+		TotalSize    uint64
+		SharedSize   uint64
+		Ratio        float32
+		DagStats     []dagStat
+	}
+	var last *summary
+	dec := json.NewDecoder(resp.Output)
+	for {
+		var out summary
+		if err := dec.Decode(&out); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		if last != nil && settings.Events != nil {
+			ev := &DagStatEvent{Size: last.TotalSize}
+			for _, d := range last.DagStats {
+				ev.Blocks += int(d.NumBlocks)
+			}
+			select {
+			case settings.Events <- ev:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		last = &out
+	}
+	if last == nil {
+		return nil, errors.New("dag/stat: empty response")
+	}
+
+	result := &DagStatResult{
+		UniqueBlocks: last.UniqueBlocks,
+		TotalSize:    last.TotalSize,
+		SharedSize:   last.SharedSize,
+		Ratio:        last.Ratio,
+	}
+	for _, d := range last.DagStats {
+		c, err := cid.Decode(d.Cid)
+		if err != nil {
+			return nil, err
+		}
+		result.Dags = append(result.Dags, DagStatEntry{Cid: c, Size: d.Size, NumBlocks: d.NumBlocks})
+	}
+	return result, nil
+}
+
+// DagResolve resolves path to the CID of the last block it crosses and the
+// remainder of the path inside that block.
+func (h *HttpClient) DagResolve(ctx context.Context, path string) (cid.Cid, string, error) {
+	var out struct {
+		Cid     cid.Cid
+		RemPath string
+	}
+	if err := h.Request("dag/resolve", path).Exec(ctx, &out); err != nil {
+		return cid.Undef, "", err
+	}
+	return out.Cid, out.RemPath, nil
+}
+
+func (h *HttpClient) DagImport(ctx context.Context, input string, silent, stats bool) (*DagImportOutput, error) {
+	iFd, err := os.Open(input)
 	if err != nil {
 		return nil, err
 	}
+	defer iFd.Close()
+
+	return h.DagImportWithOpts(ctx, iFd, options.Dag.Silent(silent), options.Dag.Stats(stats))
+}
 
-	fileReader, err := h.dagToFilesReader(data)
+func (h *HttpClient) DagImportWithOpts(ctx context.Context, data interface{}, opts ...options.DagImportOption) (*DagImportOutput, error) {
+	cfg, err := options.DagImportOptions(opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := h.Request("dag/import").
+	rb := h.Request("dag/import").
 		Option("pin-roots", cfg.PinRoots).
 		Option("silent", cfg.Silent).
-		Option("stats", cfg.Stats).
-		Body(fileReader).
-		Send(context.Background())
+		Option("stats", cfg.Stats)
+	if err := h.dagBody(ctx, rb, data); err != nil {
+		return nil, err
+	}
+
+	res, err := rb.Send(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -82,11 +274,18 @@ func (h *HttpClient) DagImportWithOpts(data interface{}, opts ...options.DagImpo
 	dec := json.NewDecoder(res.Output)
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		var root DagImportRoot
 		err := dec.Decode(&root)
 		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			return nil, err
+		}
 
 		if root.Stats != nil {
 			out.Stats = root.Stats
@@ -96,31 +295,67 @@ func (h *HttpClient) DagImportWithOpts(data interface{}, opts ...options.DagImpo
 		out.Roots = append(out.Roots, root)
 	}
 
-	return &out, err
+	return &out, nil
 }
 
-func (h *HttpClient) dagToFilesReader(data interface{}) (*files.MultiFileReader, error) {
-	var r io.Reader
+// dagBody sets the CAR input of a dag/import request. Strings, byte slices
+// and seekable readers are wrapped so the upload can be retried; a plain
+// io.Reader or a prepared MultiFileReader is sent once.
+func (h *HttpClient) dagBody(ctx context.Context, rb *RequestBuilder, data interface{}) error {
 	switch data := data.(type) {
 	case *files.MultiFileReader:
-		return data, nil
+		rb.Body(data)
 	case string:
-		r = strings.NewReader(data)
+		rb.BodyFunc(func() (io.Reader, error) {
+			return h.dagToFilesReader(ctx, strings.NewReader(data))
+		})
 	case []byte:
-		r = bytes.NewReader(data)
+		rb.BodyFunc(func() (io.Reader, error) {
+			return h.dagToFilesReader(ctx, bytes.NewReader(data))
+		})
+	case io.ReadSeeker:
+		offset, err := data.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		rb.BodyFunc(func() (io.Reader, error) {
+			if _, err := data.Seek(offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			// The multipart reader closes each part it finishes; keep the
+			// source open so a retry can seek back.
+			return h.dagToFilesReader(ctx, io.NopCloser(data))
+		})
 	case io.Reader:
-		r = data
+		fileReader, err := h.dagToFilesReader(ctx, data)
+		if err != nil {
+			return err
+		}
+		rb.Body(fileReader)
 	default:
-		return nil, fmt.Errorf("values of type %T cannot be handled as DAG input", data)
+		return fmt.Errorf("values of type %T cannot be handled as DAG input", data)
 	}
+	return nil
+}
 
+func (h *HttpClient) dagToFilesReader(ctx context.Context, r io.Reader) (*files.MultiFileReader, error) {
 	fr := files.NewReaderFile(r)
 	slf := files.NewSliceDirectory([]files.DirEntry{files.FileEntry("", fr)})
-	return h.newMultiFileReader(slf)
+	return h.newMultiFileReader(ctx, slf)
 }
 
-func (h *HttpClient) DagExport(hash, outputFile string) error {
-	resp, err := h.Request("dag/export", hash).Send(context.Background())
+func (h *HttpClient) DagExport(ctx context.Context, hash, outputFile string, opts ...options.DownloadOption) error {
+	settings, err := options.DownloadOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	if settings.Events != nil {
+		total = h.expectedDagSize(ctx, hash)
+	}
+
+	resp, err := h.Request("dag/export", hash).Send(ctx)
 	if err != nil {
 		return err
 	}
@@ -129,21 +364,50 @@ func (h *HttpClient) DagExport(hash, outputFile string) error {
 	if resp.Error != nil {
 		return resp.Error
 	}
-	defer resp.Output.Close()
 
 	oFd, err := os.Create(outputFile)
 	if err != nil {
-		log.Fatalf("Failed to create output CAR file: %v", err)
+		log.Errorf("Failed to create output CAR file: %v", err)
+		return err
 	}
 	defer oFd.Close()
 
-	written, err := io.Copy(oFd, resp.Output)
+	output := io.ReadCloser(resp.Output)
+	if settings.Events != nil {
+		progress := newDownloadProgress(ctx, settings.Events, hash, total)
+		output = &progressReader{r: output, p: progress, final: true}
+	}
+
+	written, err := io.Copy(oFd, &ctxReader{ctx: ctx, r: output})
 	if err != nil {
-		log.Fatalf("Failed to write output CAR file: %v", err)
+		log.Errorf("Failed to write output CAR file: %v", err)
 		_ = os.Remove(outputFile)
 		return err
 	}
 
-	fmt.Printf("io.Copy write:%v\n", written)
+	log.Debugf("exported %s to %s, %d bytes", hash, outputFile, written)
+
+	if settings.Verify {
+		if err := verifyCarFile(hash, outputFile); err != nil {
+			_ = os.Remove(outputFile)
+			return err
+		}
+	}
 	return nil
 }
+
+// verifyCarFile checks an exported CAR file against the CID it was exported
+// for.
+func verifyCarFile(hash, file string) error {
+	root, err := cid.Decode(hash)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return car.VerifyCar(bufio.NewReader(f), root)
+}
@@ -0,0 +1,150 @@
+package ipfs_api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// putLinked stores a dag-cbor object linking to a child object and returns
+// both CIDs.
+func putLinked(t *testing.T, c *HttpClient) (parent, child cid.Cid) {
+	t.Helper()
+	ctx := context.Background()
+	childNode, err := qp.BuildMap(basicnode.Prototype.Any, 1, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "name", qp.String("child"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child, err = c.DagPut(ctx, childNode); err != nil {
+		t.Fatal(err)
+	}
+
+	parentNode, err := qp.BuildMap(basicnode.Prototype.Any, 2, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "child", qp.Link(cidlink.Link{Cid: child}))
+		qp.MapEntry(ma, "n", qp.Int(1))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent, err = c.DagPut(ctx, parentNode, options.Dag.Pin(true)); err != nil {
+		t.Fatal(err)
+	}
+	return parent, child
+}
+
+func TestDagPutGet(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	parent, child := putLinked(t, c)
+	if parent.Type() != cid.DagCBOR || parent.Version() != 1 {
+		t.Errorf("parent = %s, want a dag-cbor CIDv1", parent)
+	}
+
+	for _, codec := range []string{"dag-json", "dag-cbor"} {
+		nd, err := c.DagGet(ctx, parent.String(), options.Dag.OutputCodec(codec))
+		if err != nil {
+			t.Fatalf("%s: %v", codec, err)
+		}
+		link, err := nd.LookupByString("child")
+		if err != nil {
+			t.Fatalf("%s: %v", codec, err)
+		}
+		if l, err := link.AsLink(); err != nil || !l.(cidlink.Link).Cid.Equals(child) {
+			t.Errorf("%s: child link = %v, %v", codec, l, err)
+		}
+	}
+
+	// Paths cross links to other blocks.
+	nd, err := c.DagGet(ctx, parent.String()+"/child/name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, err := nd.AsString(); err != nil || name != "child" {
+		t.Errorf("child name = %q, %v", name, err)
+	}
+
+	var n int
+	if err := c.DagGetJSON(ctx, parent.String()+"/n", &n); err != nil || n != 1 {
+		t.Errorf("n = %d, %v", n, err)
+	}
+}
+
+func TestDagResolve(t *testing.T) {
+	c, _ := newTestClient(t)
+	parent, child := putLinked(t, c)
+
+	tests := []struct {
+		path    string
+		want    cid.Cid
+		remPath string
+	}{
+		{parent.String(), parent, ""},
+		{parent.String() + "/n", parent, "n"},
+		{"/ipfs/" + parent.String() + "/child", child, ""},
+		{parent.String() + "/child/name", child, "name"},
+	}
+	for _, tt := range tests {
+		got, rem, err := c.DagResolve(context.Background(), tt.path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if !got.Equals(tt.want) || rem != tt.remPath {
+			t.Errorf("%s: resolved to %s %q, want %s %q", tt.path, got, rem, tt.want, tt.remPath)
+		}
+	}
+}
+
+func TestDagStat(t *testing.T) {
+	c, _ := newTestClient(t)
+	root := addTree(t, c)
+
+	events := make(chan interface{}, 64)
+	res, err := c.DagStat(context.Background(), root.String(), options.Dag.Events(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(events)
+
+	if len(res.Dags) != 1 || !res.Dags[0].Cid.Equals(root) {
+		t.Fatalf("dags = %+v, want the tree", res.Dags)
+	}
+	dag := res.Dags[0]
+	if int64(res.UniqueBlocks) != dag.NumBlocks || res.TotalSize != dag.Size || res.SharedSize != 0 || res.Ratio != 1 {
+		t.Errorf("result = %+v, want a single DAG without shared blocks", res)
+	}
+
+	// One event per block, the last one with the final totals.
+	var last *DagStatEvent
+	n := 0
+	for ev := range events {
+		e := ev.(*DagStatEvent)
+		n++
+		if e.Blocks != n {
+			t.Errorf("event %d counts %d blocks", n, e.Blocks)
+		}
+		if last != nil && e.Size < last.Size {
+			t.Errorf("size went down from %d to %d", last.Size, e.Size)
+		}
+		last = e
+	}
+	if int64(n) != dag.NumBlocks || last.Size != res.TotalSize {
+		t.Errorf("%d events ending at %+v, want %d ending at %d bytes", n, last, dag.NumBlocks, res.TotalSize)
+	}
+
+	// Without events the node only sends the summary.
+	plain, err := c.DagStat(context.Background(), root.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.TotalSize != res.TotalSize || plain.UniqueBlocks != res.UniqueBlocks {
+		t.Errorf("without events = %+v, want %+v", plain, res)
+	}
+}
@@ -0,0 +1,152 @@
+package ipfs_api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/urchinfs/go-urchin2-sdk/utils"
+)
+
+// Failures of RPC calls can be classified with errors.Is against these
+// sentinels and utils.ErrOffline, whichever of *Error, *StreamError or
+// *TransportError the call returned.
+var (
+	// ErrNotFound is a block, path, name, key or pin the node does not
+	// have.
+	ErrNotFound = errors.New("not found")
+	// ErrTimeout is a call that ran out of time, on the node or locally.
+	ErrTimeout = errors.New("timeout")
+	// ErrUnauthorized is a call refused by the node or by the proxy in
+	// front of it.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrCommandNotFound is a command the node does not know, usually
+	// because its version is older than the SDK.
+	ErrCommandNotFound = errors.New("command not found")
+	// ErrStreamAborted is an output the node started to send and then
+	// failed, reported in the X-Stream-Error trailer.
+	ErrStreamAborted = errors.New("stream aborted")
+)
+
+// Error types of Kubo RPC errors, see the Code field of Error. They follow
+// cmds.ErrorType of go-ipfs-cmds, which has no code for missing content.
+const (
+	codeRateLimited = 3
+	codeForbidden   = 4
+)
+
+// notFoundMessages are the texts Kubo uses when something is missing, which
+// it reports with the generic error code.
+var notFoundMessages = []string{
+	"not found",
+	"could not find",
+	"no link named",
+	"does not exist",
+	"doesn't exist",
+	"could not resolve name",
+	"no key by the given name",
+	"no key named",
+	"is not pinned",
+	"not pinned or pinned indirectly",
+}
+
+// classify tells whether msg, the text of an error reported by the node,
+// matches target.
+func classify(msg string, target error) bool {
+	msg = strings.ToLower(msg)
+	switch target {
+	case ErrNotFound:
+		for _, m := range notFoundMessages {
+			if strings.Contains(msg, m) {
+				return true
+			}
+		}
+	case ErrTimeout:
+		return strings.Contains(msg, "context deadline exceeded") ||
+			strings.Contains(msg, "timed out") ||
+			strings.Contains(msg, "timeout")
+	case utils.ErrOffline:
+		return strings.Contains(msg, "must be run in online mode")
+	}
+	return false
+}
+
+// Is matches the error against the sentinels of the package, from its
+// status, code and message. Kubo only answers 404 for unknown commands.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrCommandNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized ||
+			e.StatusCode == http.StatusForbidden ||
+			e.Code == codeForbidden
+	case ErrNotFound:
+		return e.StatusCode != http.StatusNotFound && e.Code != codeRateLimited &&
+			classify(e.Message, target)
+	case ErrTimeout:
+		return e.StatusCode == http.StatusRequestTimeout ||
+			e.StatusCode == http.StatusGatewayTimeout ||
+			classify(e.Message, target)
+	}
+	return classify(e.Message, target)
+}
+
+// StreamError is a failure the node reported after it started to send the
+// output of Command.
+type StreamError struct {
+	Command string
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	if e.Command == "" {
+		return e.Message
+	}
+	return e.Command + ": " + e.Message
+}
+
+func (e *StreamError) Is(target error) bool {
+	return target == ErrStreamAborted || classify(e.Message, target)
+}
+
+// TransportError is a call that never got an answer from the node. Err is
+// the error of the HTTP client.
+type TransportError struct {
+	Command string
+	Err     error
+}
+
+func (e *TransportError) Error() string {
+	return e.Command + ": " + e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// Is classifies a refused connection as the node being offline, that is its
+// daemon not running, and timeouts of the connection or the context as
+// ErrTimeout.
+func (e *TransportError) Is(target error) bool {
+	switch target {
+	case ErrTimeout:
+		var netErr net.Error
+		return errors.Is(e.Err, context.DeadlineExceeded) ||
+			errors.As(e.Err, &netErr) && netErr.Timeout()
+	case utils.ErrOffline:
+		return errors.Is(e.Err, syscall.ECONNREFUSED)
+	}
+	return false
+}
+
+// isPermanent tells whether err will fail the same way however often the
+// call is repeated.
+func isPermanent(err error) bool {
+	return errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrUnauthorized) ||
+		errors.Is(err, ErrCommandNotFound)
+}
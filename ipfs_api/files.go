@@ -0,0 +1,250 @@
+package ipfs_api
+
+import (
+	"context"
+	"io"
+
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/go-cid"
+	sdkcid "github.com/urchinfs/go-urchin2-sdk/cid"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// MfsEntry is an entry of an MFS directory. Cid and Size are only filled
+// when listing with options.Files.Long.
+type MfsEntry struct {
+	Name string
+	Type sdkcid.FileType
+	Size uint64
+	Cid  cid.Cid
+}
+
+// MfsStat describes a file or directory in MFS.
+type MfsStat struct {
+	Cid            cid.Cid
+	Type           sdkcid.FileType
+	Size           uint64
+	CumulativeSize uint64
+	Blocks         int
+}
+
+func mfsFileType(typ string) sdkcid.FileType {
+	switch typ {
+	case "file":
+		return sdkcid.TFile
+	case "directory":
+		return sdkcid.TDirectory
+	case "symlink":
+		return sdkcid.TSymlink
+	default:
+		return sdkcid.TUnknown
+	}
+}
+
+// dagOptions sets the options shared by commands creating MFS nodes.
+func dagOptions(rb *RequestBuilder, settings *options.FilesSettings) *RequestBuilder {
+	rb.Option("flush", settings.Flush)
+	if settings.CidVersion >= 0 {
+		rb.Option("cid-version", settings.CidVersion)
+	}
+	if settings.Hash != "" {
+		rb.Option("hash", settings.Hash)
+	}
+	return rb
+}
+
+// FilesMkdir creates a directory in MFS.
+func (h *HttpClient) FilesMkdir(ctx context.Context, path string, opts ...options.FilesOption) error {
+	settings, err := options.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	rb := h.Request("files/mkdir", path).Option("parents", settings.Parents)
+	return dagOptions(rb, settings).Exec(ctx, nil)
+}
+
+// FilesWrite streams r into the MFS file at path, starting at
+// options.Files.Offset. Use options.Files.Create to create a missing file
+// and options.Files.Truncate to replace its content.
+func (h *HttpClient) FilesWrite(ctx context.Context, path string, r io.Reader, opts ...options.FilesOption) error {
+	settings, err := options.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	body, err := h.nodeBody(ctx, "", files.NewReaderFile(r))
+	if err != nil {
+		return err
+	}
+
+	rb := h.Request("files/write", path).
+		Option("offset", settings.Offset).
+		Option("create", settings.Create).
+		Option("parents", settings.Parents).
+		Option("truncate", settings.Truncate)
+	if settings.Count >= 0 {
+		rb.Option("count", settings.Count)
+	}
+	if settings.RawLeavesSet {
+		rb.Option("raw-leaves", settings.RawLeaves)
+	}
+	// Sent rather than executed so RequestTimeout does not cut off a long
+	// upload.
+	resp, err := dagOptions(rb, settings).Body(body).Send(ctx)
+	if err != nil {
+		return err
+	}
+	lateErr := resp.Close()
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return lateErr
+}
+
+// FilesRead streams the content of the MFS file at path, honouring
+// options.Files.Offset and options.Files.Count.
+func (h *HttpClient) FilesRead(ctx context.Context, path string, opts ...options.FilesOption) (io.ReadCloser, error) {
+	settings, err := options.FilesOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := h.Request("files/read", path).Option("offset", settings.Offset)
+	if settings.Count >= 0 {
+		rb.Option("count", settings.Count)
+	}
+
+	resp, err := rb.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Output, nil
+}
+
+// FilesLs lists the MFS directory at path.
+func (h *HttpClient) FilesLs(ctx context.Context, path string, opts ...options.FilesOption) ([]MfsEntry, error) {
+	settings, err := options.FilesOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Entries []struct {
+			Name string
+			Type int
+			Size uint64
+			Hash string
+		}
+	}
+	err = h.Request("files/ls", path).
+		Option("long", settings.Long).
+		Exec(ctx, &out)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]MfsEntry, 0, len(out.Entries))
+	for _, e := range out.Entries {
+		entry := MfsEntry{Name: e.Name, Size: e.Size}
+		if settings.Long {
+			// MFS reports 0 for files and 1 for directories.
+			entry.Type = sdkcid.TFile
+			if e.Type == 1 {
+				entry.Type = sdkcid.TDirectory
+			}
+		}
+		if e.Hash != "" {
+			if entry.Cid, err = cid.Decode(e.Hash); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// FilesStat describes the file or directory at path. Besides MFS paths it
+// accepts /ipfs/ paths.
+func (h *HttpClient) FilesStat(ctx context.Context, path string) (*MfsStat, error) {
+	var out struct {
+		Hash           string
+		Size           uint64
+		CumulativeSize uint64
+		Blocks         int
+		Type           string
+	}
+	if err := h.Request("files/stat", path).Exec(ctx, &out); err != nil {
+		return nil, err
+	}
+
+	c, err := cid.Decode(out.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return &MfsStat{
+		Cid:            c,
+		Type:           mfsFileType(out.Type),
+		Size:           out.Size,
+		CumulativeSize: out.CumulativeSize,
+		Blocks:         out.Blocks,
+	}, nil
+}
+
+// FilesCp copies src, an MFS or /ipfs/ path, to the MFS path dst.
+func (h *HttpClient) FilesCp(ctx context.Context, src, dst string, opts ...options.FilesOption) error {
+	settings, err := options.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	return h.Request("files/cp", src, dst).
+		Option("parents", settings.Parents).
+		Option("flush", settings.Flush).
+		Exec(ctx, nil)
+}
+
+// FilesMv moves src to dst within MFS.
+func (h *HttpClient) FilesMv(ctx context.Context, src, dst string, opts ...options.FilesOption) error {
+	settings, err := options.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	return h.Request("files/mv", src, dst).
+		Option("flush", settings.Flush).
+		Exec(ctx, nil)
+}
+
+// FilesRm removes path from MFS. Directories need options.Files.Recursive.
+func (h *HttpClient) FilesRm(ctx context.Context, path string, opts ...options.FilesOption) error {
+	settings, err := options.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	return h.Request("files/rm", path).
+		Option("recursive", settings.Recursive).
+		Option("force", settings.Force).
+		Exec(ctx, nil)
+}
+
+// FilesFlush writes pending changes under path to the MFS root and returns
+// the resulting CID of path.
+func (h *HttpClient) FilesFlush(ctx context.Context, path string) (cid.Cid, error) {
+	var out struct{ Cid string }
+	if err := h.Request("files/flush", path).Exec(ctx, &out); err != nil {
+		return cid.Undef, err
+	}
+	return cid.Decode(out.Cid)
+}
+
+// FilesLink gives existing content, such as the root of a DagImport, the
+// stable MFS path dst. Missing parent directories are created.
+func (h *HttpClient) FilesLink(ctx context.Context, c cid.Cid, dst string, opts ...options.FilesOption) error {
+	opts = append([]options.FilesOption{options.Files.Parents(true)}, opts...)
+	return h.FilesCp(ctx, "/ipfs/"+c.String(), dst, opts...)
+}
@@ -0,0 +1,215 @@
+package ipfs_api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	sdkcid "github.com/urchinfs/go-urchin2-sdk/cid"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+func filesRead(t *testing.T, c *HttpClient, path string, opts ...options.FilesOption) string {
+	t.Helper()
+	r, err := c.FilesRead(context.Background(), path, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFilesWrite(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	write := func(data string, opts ...options.FilesOption) error {
+		return c.FilesWrite(ctx, "/docs/a.txt", strings.NewReader(data), opts...)
+	}
+
+	if err := write("hello world"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("write to a missing file = %v, want ErrNotFound", err)
+	}
+	if err := write("hello world", options.Files.Create(true), options.Files.Parents(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		opts []options.FilesOption
+		want string
+	}{
+		{"overwrite", "HELLO", nil, "HELLO world"},
+		{"offset", "there", []options.FilesOption{options.Files.Offset(6)}, "HELLO there"},
+		{"count", "WORLDS", []options.FilesOption{options.Files.Offset(6), options.Files.Count(3)}, "HELLO WORre"},
+		{"append", "!", []options.FilesOption{options.Files.Offset(11)}, "HELLO WORre!"},
+		{"truncate", "new", []options.FilesOption{options.Files.Truncate(true)}, "new"},
+	}
+	for _, tt := range tests {
+		if err := write(tt.data, tt.opts...); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := filesRead(t, c, "/docs/a.txt"); got != tt.want {
+			t.Errorf("%s: file = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	stat, err := c.FilesStat(ctx, "/docs/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Type != sdkcid.TFile || stat.Size != 3 {
+		t.Errorf("stat = %+v, want a 3 byte file", stat)
+	}
+
+	err = c.FilesWrite(ctx, "/raw.bin", strings.NewReader("raw"),
+		options.Files.Create(true), options.Files.CidVersion(1), options.Files.RawLeaves(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat, err := c.FilesStat(ctx, "/raw.bin"); err != nil || stat.Cid.Version() != 1 {
+		t.Errorf("stat of a CIDv1 file = %+v, %v", stat, err)
+	}
+}
+
+func TestFilesRead(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	if err := c.FilesWrite(ctx, "/a.txt", strings.NewReader("hello world"), options.Files.Create(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := filesRead(t, c, "/a.txt", options.Files.Offset(6)); got != "world" {
+		t.Errorf("from offset 6 = %q", got)
+	}
+	if got := filesRead(t, c, "/a.txt", options.Files.Offset(2), options.Files.Count(3)); got != "llo" {
+		t.Errorf("3 bytes from offset 2 = %q", got)
+	}
+	if _, err := c.FilesRead(ctx, "/a.txt", options.Files.Offset(12)); err == nil {
+		t.Error("read past the end succeeded")
+	}
+	if _, err := c.FilesRead(ctx, "/missing.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("read of a missing file = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFilesMkdirLs(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	if err := c.FilesMkdir(ctx, "/a/b"); err == nil {
+		t.Error("mkdir without its parent succeeded")
+	}
+	if err := c.FilesMkdir(ctx, "/a/b", options.Files.Parents(true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.FilesWrite(ctx, "/a/f.txt", strings.NewReader("file"), options.Files.Create(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	short, err := c.FilesLs(ctx, "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(short) != 2 || short[0].Name != "b" || short[1].Name != "f.txt" || short[0].Cid.Defined() {
+		t.Errorf("listing = %+v, want b and f.txt by name only", short)
+	}
+
+	long, err := c.FilesLs(ctx, "/a", options.Files.Long(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]MfsEntry)
+	for _, e := range long {
+		byName[e.Name] = e
+	}
+	if b := byName["b"]; b.Type != sdkcid.TDirectory || !b.Cid.Defined() {
+		t.Errorf("b = %+v, want a directory with its CID", b)
+	}
+	if f := byName["f.txt"]; f.Type != sdkcid.TFile || f.Size != 4 || !f.Cid.Defined() {
+		t.Errorf("f.txt = %+v, want a 4 byte file with its CID", f)
+	}
+
+	stat, err := c.FilesStat(ctx, "/a/b")
+	if err != nil || stat.Type != sdkcid.TDirectory || !stat.Cid.Equals(byName["b"].Cid) {
+		t.Errorf("stat of /a/b = %+v, %v", stat, err)
+	}
+}
+
+func TestFilesLink(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	root := addTree(t, c)
+
+	if err := c.FilesLink(ctx, root, "/imports/tree"); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := c.FilesStat(ctx, "/imports/tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stat.Cid.Equals(root) || stat.Type != sdkcid.TDirectory {
+		t.Errorf("stat = %+v, want the directory %s", stat, root)
+	}
+	if got := filesRead(t, c, "/imports/tree/a.txt"); got != "hello world" {
+		t.Errorf("a.txt = %q", got)
+	}
+
+	// The linked tree is part of the MFS root once flushed.
+	flushed, err := c.FilesFlush(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var linked cid.Cid
+	if entries, err := c.FilesLs(ctx, "/imports", options.Files.Long(true)); err == nil && len(entries) == 1 {
+		linked = entries[0].Cid
+	}
+	if !linked.Equals(root) {
+		t.Errorf("/imports links to %s, want %s", linked, root)
+	}
+	if top, err := c.FilesStat(ctx, "/"); err != nil || !top.Cid.Equals(flushed) {
+		t.Errorf("root = %+v, %v; want %s", top, err, flushed)
+	}
+
+	if err := c.FilesLink(ctx, root, "/imports/tree"); err == nil {
+		t.Error("link over an existing entry succeeded")
+	}
+}
+
+func TestFilesMvRm(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	if err := c.FilesWrite(ctx, "/dir/a.txt", strings.NewReader("a"), options.Files.Create(true), options.Files.Parents(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.FilesMv(ctx, "/dir/a.txt", "/dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := filesRead(t, c, "/dir/b.txt"); got != "a" {
+		t.Errorf("moved file = %q", got)
+	}
+	if _, err := c.FilesStat(ctx, "/dir/a.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("stat of the old path = %v, want ErrNotFound", err)
+	}
+
+	if err := c.FilesRm(ctx, "/dir"); err == nil {
+		t.Error("directory removed without Recursive")
+	}
+	if err := c.FilesRm(ctx, "/dir", options.Files.Recursive(true)); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := c.FilesLs(ctx, "/"); err != nil || len(entries) != 0 {
+		t.Errorf("root after rm = %+v, %v", entries, err)
+	}
+	if err := c.FilesRm(ctx, "/dir", options.Files.Force(true)); err != nil {
+		t.Errorf("forced rm of a missing path = %v", err)
+	}
+}
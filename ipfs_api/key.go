@@ -0,0 +1,130 @@
+package ipfs_api
+
+import (
+	"context"
+	"io"
+
+	"github.com/ipfs/boxo/files"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// Key is a key of the node keystore. Id is the IPNS name of the key.
+type Key struct {
+	Name string
+	Id   string
+}
+
+// KeyRenameResult describes a renamed key.
+type KeyRenameResult struct {
+	Was       string
+	Now       string
+	Id        string
+	Overwrite bool
+}
+
+// KeyGen generates a new key called name.
+func (h *HttpClient) KeyGen(ctx context.Context, name string, opts ...options.KeyGenOption) (*Key, error) {
+	settings, err := options.KeyGenOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := h.Request("key/gen", name).Option("type", settings.Type)
+	if settings.Size > 0 {
+		rb.Option("size", settings.Size)
+	}
+
+	var out Key
+	if err := rb.Exec(ctx, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// KeyImport imports the private key read from r under name.
+func (h *HttpClient) KeyImport(ctx context.Context, name string, r io.Reader, opts ...options.KeyImportOption) (*Key, error) {
+	settings, err := options.KeyImportOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := h.nodeBody(ctx, "", files.NewReaderFile(r))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.Request("key/import", name).
+		Option("format", settings.Format).
+		Option("allow-any-key-type", settings.AllowAnyKeyType).
+		Body(body).
+		Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var out Key
+	if err := resp.Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// KeyExport returns the private key called name. Nodes that only allow
+// exporting keys from their own command line reject the request.
+func (h *HttpClient) KeyExport(ctx context.Context, name string, opts ...options.KeyExportOption) ([]byte, error) {
+	settings, err := options.KeyExportOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.Request("key/export", name).
+		Option("format", settings.Format).
+		Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return io.ReadAll(resp.Output)
+}
+
+// KeyRename renames the key oldName to newName.
+func (h *HttpClient) KeyRename(ctx context.Context, oldName, newName string, opts ...options.KeyRenameOption) (*KeyRenameResult, error) {
+	settings, err := options.KeyRenameOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var out KeyRenameResult
+	err = h.Request("key/rename", oldName, newName).
+		Option("force", settings.Force).
+		Exec(ctx, &out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type keyList struct {
+	Keys []Key
+}
+
+// KeyList lists the keys of the node keystore.
+func (h *HttpClient) KeyList(ctx context.Context) ([]Key, error) {
+	var out keyList
+	if err := h.Request("key/list").Option("l", true).Exec(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out.Keys, nil
+}
+
+// KeyRm removes the given keys and returns them.
+func (h *HttpClient) KeyRm(ctx context.Context, names ...string) ([]Key, error) {
+	var out keyList
+	if err := h.Request("key/rm", names...).Option("l", true).Exec(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out.Keys, nil
+}
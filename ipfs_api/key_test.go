@@ -0,0 +1,140 @@
+package ipfs_api
+
+import (
+	"bytes"
+	"context"
+	stded25519 "crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// ipnsName is the name Kubo reports for the key sk.
+func ipnsName(t *testing.T, sk crypto.PrivKey) string {
+	t.Helper()
+	pid, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ipns.NameFromPeer(pid).String()
+}
+
+func TestKeyGenList(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	a, err := c.KeyGen(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ipns.NameFromString(a.Id); err != nil || a.Name != "a" {
+		t.Errorf("key = %+v, want a with an IPNS name: %v", a, err)
+	}
+	b, err := c.KeyGen(ctx, "b", options.Key.Type("rsa"), options.Key.Size(2048))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.KeyGen(ctx, "a"); err == nil {
+		t.Error("second key named a generated")
+	}
+	if _, err := c.KeyGen(ctx, "self"); err == nil {
+		t.Error("key named self generated")
+	}
+
+	keys, err := c.KeyList(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || keys[0].Name != "self" || keys[1] != *a || keys[2] != *b {
+		t.Errorf("keys = %+v, want self, %+v and %+v", keys, a, b)
+	}
+}
+
+func TestKeyImport(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	sk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := crypto.MarshalPrivateKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := c.KeyImport(ctx, "libp2p", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ipnsName(t, sk); key.Id != want {
+		t.Errorf("imported key = %s, want %s", key.Id, want)
+	}
+	if _, err := c.KeyImport(ctx, "libp2p", bytes.NewReader(data)); err == nil {
+		t.Error("key imported over an existing one")
+	}
+
+	_, std, err := stded25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(std)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if _, err := c.KeyImport(ctx, "pem", bytes.NewReader(pemData)); err == nil {
+		t.Error("PEM key imported as a libp2p key")
+	}
+	key, err = c.KeyImport(ctx, "pem", bytes.NewReader(pemData), options.Key.ImportFormat(options.KeyFormatPem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, err = crypto.UnmarshalEd25519PrivateKey(std)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ipnsName(t, sk); key.Id != want {
+		t.Errorf("imported PEM key = %s, want %s", key.Id, want)
+	}
+}
+
+func TestKeyRenameRm(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	a, err := c.KeyGen(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.KeyGen(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.KeyRename(ctx, "a", "b"); err == nil {
+		t.Error("rename over an existing key succeeded without Force")
+	}
+	res, err := c.KeyRename(ctx, "a", "b", options.Key.Force(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (KeyRenameResult{Was: "a", Now: "b", Id: a.Id, Overwrite: true}); *res != want {
+		t.Errorf("rename = %+v, want %+v", res, want)
+	}
+
+	if _, err := c.KeyRm(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("rm of a renamed key = %v, want ErrNotFound", err)
+	}
+	removed, err := c.KeyRm(ctx, "b")
+	if err != nil || len(removed) != 1 || removed[0].Id != a.Id {
+		t.Fatalf("rm = %+v, %v; want b with the id of a", removed, err)
+	}
+	if keys, err := c.KeyList(ctx); err != nil || len(keys) != 1 {
+		t.Errorf("keys after rm = %+v, %v; want only self", keys, err)
+	}
+}
@@ -0,0 +1,155 @@
+package ipfs_api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// IpnsEntry is an IPNS name and the path it points to. Validity is the end
+// of life of the record and TTL the caching hint given to resolvers; both
+// are zero when unknown.
+type IpnsEntry struct {
+	Name     string
+	Value    string
+	Validity time.Time
+	TTL      time.Duration
+	Sequence uint64
+}
+
+// NamePublish publishes path, typically /ipfs/<cid>, under the IPNS name of
+// the key selected with options.Name.Key. Validity is computed from the
+// requested lifetime at the time of publishing.
+func (h *HttpClient) NamePublish(ctx context.Context, path string, opts ...options.NamePublishOption) (*IpnsEntry, error) {
+	settings, err := options.NamePublishOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := h.Request("name/publish", ipfsPath(path)).
+		Option("key", settings.Key).
+		Option("lifetime", settings.ValidTime.String()).
+		Option("resolve", settings.Resolve).
+		Option("allow-offline", settings.AllowOffline)
+	if settings.TTL > 0 {
+		rb.Option("ttl", settings.TTL.String())
+	}
+
+	start := time.Now()
+	var out struct {
+		Name  string
+		Value string
+	}
+	if err := rb.Exec(ctx, &out); err != nil {
+		return nil, err
+	}
+	return &IpnsEntry{
+		Name:     out.Name,
+		Value:    out.Value,
+		Validity: start.Add(settings.ValidTime),
+		TTL:      settings.TTL,
+	}, nil
+}
+
+// NameResolve resolves an IPNS name, with or without the /ipns/ prefix.
+// The node only reports the resolved path; use NameRecord to learn the
+// validity of the record.
+func (h *HttpClient) NameResolve(ctx context.Context, name string, opts ...options.NameResolveOption) (*IpnsEntry, error) {
+	settings, err := options.NameResolveOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := h.Request("name/resolve", name).
+		Option("recursive", settings.Recursive).
+		Option("nocache", !settings.Cache)
+	if settings.DhtRecordCount > 0 {
+		rb.Option("dht-record-count", settings.DhtRecordCount)
+	}
+	if settings.DhtTimeout > 0 {
+		rb.Option("dht-timeout", settings.DhtTimeout.String())
+	}
+
+	var out struct{ Path string }
+	if err := rb.Exec(ctx, &out); err != nil {
+		return nil, err
+	}
+	return &IpnsEntry{
+		Name:  strings.TrimPrefix(name, "/ipns/"),
+		Value: out.Path,
+	}, nil
+}
+
+// routingValueEvent is the type of routing/get query events carrying the
+// value found.
+const routingValueEvent = 5
+
+// NameRecord fetches the signed IPNS record of name from the routing system
+// and returns its content once the signature has been checked.
+func (h *HttpClient) NameRecord(ctx context.Context, name string) (*IpnsEntry, error) {
+	n, err := ipns.NameFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.Request("routing/get", n.AsPath().String()).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	var data []byte
+	dec := json.NewDecoder(resp.Output)
+	for data == nil {
+		var event struct {
+			Type  int
+			Extra string
+		}
+		if err := dec.Decode(&event); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("%w: no IPNS record for %s", ErrNotFound, name)
+			}
+			return nil, err
+		}
+		if event.Type != routingValueEvent || event.Extra == "" {
+			continue
+		}
+		if data, err = base64.StdEncoding.DecodeString(event.Extra); err != nil {
+			return nil, err
+		}
+	}
+
+	rec, err := ipns.UnmarshalRecord(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := ipns.ValidateWithName(rec, n); err != nil {
+		return nil, err
+	}
+
+	entry := &IpnsEntry{Name: n.String()}
+	value, err := rec.Value()
+	if err != nil {
+		return nil, err
+	}
+	entry.Value = value.String()
+	if entry.Validity, err = rec.Validity(); err != nil {
+		return nil, err
+	}
+	if entry.Sequence, err = rec.Sequence(); err != nil {
+		return nil, err
+	}
+	// Records without a TTL are valid, the node default applies.
+	entry.TTL, _ = rec.TTL()
+	return entry, nil
+}
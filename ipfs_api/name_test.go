@@ -0,0 +1,142 @@
+package ipfs_api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	caopts "github.com/urchinfs/go-urchin2-sdk/cid/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+func addText(t *testing.T, c *HttpClient, text string) cid.Cid {
+	t.Helper()
+	res, err := c.AddBytes(context.Background(), "f", []byte(text), caopts.Unixfs.Wrap(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.Root
+}
+
+func TestNamePublishResolve(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	content := addText(t, c, "published")
+	key, err := c.KeyGen(ctx, "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := c.NamePublish(ctx, content.String(), options.Name.Key("site"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Name != key.Id || entry.Value != "/ipfs/"+content.String() {
+		t.Errorf("published %+v, want %s under %s", entry, content, key.Id)
+	}
+
+	for _, name := range []string{key.Id, "/ipns/" + key.Id} {
+		got, err := c.NameResolve(ctx, name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got.Name != key.Id || got.Value != entry.Value {
+			t.Errorf("%s resolved to %+v", name, got)
+		}
+	}
+
+	// A name pointing to another one.
+	if _, err := c.KeyGen(ctx, "alias"); err != nil {
+		t.Fatal(err)
+	}
+	alias, err := c.NamePublish(ctx, "/ipns/"+key.Id, options.Name.Key("alias"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.NameResolve(ctx, alias.Name); err != nil || got.Value != entry.Value {
+		t.Errorf("recursive resolve = %+v, %v; want %s", got, err, entry.Value)
+	}
+	if got, err := c.NameResolve(ctx, alias.Name, options.Name.Recursive(false)); err != nil || got.Value != "/ipns/"+key.Id {
+		t.Errorf("one step resolve = %+v, %v; want /ipns/%s", got, err, key.Id)
+	}
+
+	unpublished, err := c.KeyGen(ctx, "unpublished")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.NameResolve(ctx, unpublished.Id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("resolve of an unpublished name = %v, want ErrNotFound", err)
+	}
+	if _, err := c.NamePublish(ctx, content.String(), options.Name.Key("missing")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("publish with a missing key = %v, want ErrNotFound", err)
+	}
+}
+
+func TestNameRecord(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	first, second := addText(t, c, "first"), addText(t, c, "second")
+
+	start := time.Now()
+	published, err := c.NamePublish(ctx, first.String(), options.Name.ValidTime(time.Hour), options.Name.TTL(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := c.NameRecord(ctx, published.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Name != published.Name || rec.Value != published.Value || rec.Sequence != 0 || rec.TTL != time.Minute {
+		t.Errorf("record = %+v, want %+v with sequence 0", rec, published)
+	}
+	if rec.Validity.Before(start.Add(time.Hour)) || rec.Validity.After(time.Now().Add(time.Hour)) {
+		t.Errorf("record valid until %s, want an hour after publishing", rec.Validity)
+	}
+
+	// The sequence only moves when the value changes.
+	for i, p := range []cid.Cid{first, second, second} {
+		if _, err := c.NamePublish(ctx, p.String()); err != nil {
+			t.Fatal(err)
+		}
+		want := uint64(0)
+		if i > 0 {
+			want = 1
+		}
+		if rec, err := c.NameRecord(ctx, published.Name); err != nil || rec.Sequence != want {
+			t.Errorf("publish %d: record = %+v, %v; want sequence %d", i, rec, err, want)
+		}
+	}
+
+	key, err := c.KeyGen(ctx, "unpublished")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.NameRecord(ctx, key.Id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("record of an unpublished name = %v, want ErrNotFound", err)
+	}
+	if _, err := c.NameRecord(ctx, "not-a-name"); err == nil {
+		t.Error("record of an invalid name fetched")
+	}
+}
+
+func TestNameRecordWithoutValue(t *testing.T) {
+	// Nodes may end the query without sending the value.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Type":0,"ID":"peer"}` + "\n"))
+	}))
+	defer ts.Close()
+	c, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := "k51qzi5uqu5dlvj2baxnqndepeb86cbk3ng7n3i46uzyxzyqj2xjonzllnv0v8"
+	if _, err := c.NameRecord(context.Background(), name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("record = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,99 @@
+package options
+
+type BlockPutSettings struct {
+	Codec         string
+	MhType        string
+	MhLength      int
+	Pin           bool
+	AllowBigBlock bool
+}
+
+type BlockRmSettings struct {
+	Force bool
+}
+
+type (
+	BlockPutOption func(opts *BlockPutSettings) error
+	BlockRmOption  func(opts *BlockRmSettings) error
+)
+
+func BlockPutOptions(opts ...BlockPutOption) (*BlockPutSettings, error) {
+	options := &BlockPutSettings{
+		Codec:         "raw",
+		MhType:        "sha2-256",
+		MhLength:      -1,
+		Pin:           false,
+		AllowBigBlock: false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func BlockRmOptions(opts ...BlockRmOption) (*BlockRmSettings, error) {
+	options := &BlockRmSettings{
+		Force: false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type blockOpts struct{}
+
+var Block blockOpts
+
+// Codec is the multicodec name of the stored blocks, e.g. "raw" (default),
+// "dag-pb" or "dag-cbor".
+func (blockOpts) Codec(codec string) BlockPutOption {
+	return func(opts *BlockPutSettings) error {
+		opts.Codec = codec
+		return nil
+	}
+}
+
+// Hash is the multihash function used to compute the CIDs, "sha2-256" by
+// default. length truncates the digest, -1 keeps the full length.
+func (blockOpts) Hash(mhType string, length int) BlockPutOption {
+	return func(opts *BlockPutSettings) error {
+		opts.MhType = mhType
+		opts.MhLength = length
+		return nil
+	}
+}
+
+// Pin pins the stored blocks.
+func (blockOpts) Pin(pin bool) BlockPutOption {
+	return func(opts *BlockPutSettings) error {
+		opts.Pin = pin
+		return nil
+	}
+}
+
+// AllowBigBlock accepts blocks larger than the 1MiB the network exchanges.
+func (blockOpts) AllowBigBlock(allow bool) BlockPutOption {
+	return func(opts *BlockPutSettings) error {
+		opts.AllowBigBlock = allow
+		return nil
+	}
+}
+
+// Force ignores blocks that do not exist when removing.
+func (blockOpts) Force(force bool) BlockRmOption {
+	return func(opts *BlockRmSettings) error {
+		opts.Force = force
+		return nil
+	}
+}
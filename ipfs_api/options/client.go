@@ -0,0 +1,288 @@
+package options
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// TokenSource supplies bearer tokens for authenticating RPC requests. Token
+// is called before every request, so implementations may refresh expired
+// tokens.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+type staticToken string
+
+func (t staticToken) Token(_ context.Context) (string, error) {
+	return string(t), nil
+}
+
+type ClientSettings struct {
+	HTTPClient *http.Client
+	Headers    map[string]string
+
+	Username    string
+	Password    string
+	TokenSource TokenSource
+
+	TLSConfig *tls.Config
+
+	// Connection settings, applied to the transport built by NewClient.
+	// They are ignored when an HTTPClient is supplied. With keep-alives
+	// enabled, a MaxIdleConnsPerHost of 0 means MaxIdleConns, since all
+	// requests go to the same node.
+	KeepAlive             bool
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	IdleConnTimeout       time.Duration
+	DialTimeout           time.Duration
+	ResponseHeaderTimeout time.Duration
+	Proxy                 func(*http.Request) (*url.URL, error)
+
+	RequestTimeout time.Duration
+}
+
+type ClientOption func(opts *ClientSettings) error
+
+func ClientOptions(opts ...ClientOption) (*ClientSettings, error) {
+	options := &ClientSettings{
+		HTTPClient: nil,
+		Headers:    make(map[string]string),
+		TLSConfig:  nil,
+
+		KeepAlive:             false,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   0,
+		IdleConnTimeout:       90 * time.Second,
+		DialTimeout:           30 * time.Second,
+		ResponseHeaderTimeout: 0,
+		Proxy:                 http.ProxyFromEnvironment,
+
+		RequestTimeout: 0,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Username != "" && options.TokenSource != nil {
+		return nil, errors.New("basic auth and bearer token are mutually exclusive")
+	}
+	// net/http keeps only 2 idle connections per host by default, which
+	// would defeat keep-alives.
+	if options.KeepAlive && options.MaxIdleConnsPerHost == 0 {
+		options.MaxIdleConnsPerHost = options.MaxIdleConns
+	}
+
+	return options, nil
+}
+
+func (s *ClientSettings) tlsConfig() *tls.Config {
+	if s.TLSConfig == nil {
+		s.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return s.TLSConfig
+}
+
+type clientOpts struct{}
+
+var Client clientOpts
+
+// HTTPClient makes the client send requests through c instead of building
+// its own. TLS options are applied to a copy of c's transport.
+func (clientOpts) HTTPClient(c *http.Client) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.HTTPClient = c
+		return nil
+	}
+}
+
+// Header adds a header sent with every request.
+func (clientOpts) Header(name, value string) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.Headers[name] = value
+		return nil
+	}
+}
+
+// BasicAuth authenticates every request with HTTP basic auth, matching a
+// "Basic" entry in Kubo's API.Authorizations.
+func (clientOpts) BasicAuth(username, password string) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.Username = username
+		opts.Password = password
+		return nil
+	}
+}
+
+// BearerToken authenticates every request with a fixed bearer token.
+func (clientOpts) BearerToken(token string) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.TokenSource = staticToken(token)
+		return nil
+	}
+}
+
+// TokenSource authenticates every request with a bearer token fetched from
+// source right before the request is sent.
+func (clientOpts) TokenSource(source TokenSource) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.TokenSource = source
+		return nil
+	}
+}
+
+// TLSConfig sets the TLS configuration and switches the endpoint to https.
+// Later CA and certificate options are added on top of cfg.
+func (clientOpts) TLSConfig(cfg *tls.Config) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.TLSConfig = cfg.Clone()
+		return nil
+	}
+}
+
+// RootCAs sets the certificate pool used to verify the server.
+func (clientOpts) RootCAs(pool *x509.CertPool) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.tlsConfig().RootCAs = pool
+		return nil
+	}
+}
+
+// CACertFile adds the PEM encoded certificates in path to the system pool
+// used to verify the server.
+func (clientOpts) CACertFile(path string) ClientOption {
+	return func(opts *ClientSettings) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		cfg := opts.tlsConfig()
+		if cfg.RootCAs == nil {
+			if cfg.RootCAs, err = x509.SystemCertPool(); err != nil {
+				cfg.RootCAs = x509.NewCertPool()
+			}
+		}
+		if !cfg.RootCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in %s", path)
+		}
+		return nil
+	}
+}
+
+// ClientCertificate presents the key pair in certFile and keyFile to the
+// server for mutual TLS.
+func (clientOpts) ClientCertificate(certFile, keyFile string) ClientOption {
+	return func(opts *ClientSettings) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+
+		cfg := opts.tlsConfig()
+		cfg.Certificates = append(cfg.Certificates, cert)
+		return nil
+	}
+}
+
+// KeepAlive enables reusing connections between requests.
+func (clientOpts) KeepAlive(enabled bool) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.KeepAlive = enabled
+		return nil
+	}
+}
+
+// MaxIdleConns sets how many idle connections are kept in total and per
+// host, and enables keep-alives. A perHost of 0 keeps up to total.
+func (clientOpts) MaxIdleConns(total, perHost int) ClientOption {
+	return func(opts *ClientSettings) error {
+		if total < 0 || perHost < 0 {
+			return errors.New("idle connection limits must not be negative")
+		}
+		opts.KeepAlive = true
+		opts.MaxIdleConns = total
+		opts.MaxIdleConnsPerHost = perHost
+		return nil
+	}
+}
+
+// IdleConnTimeout sets how long an idle connection is kept before it is
+// closed, and enables keep-alives.
+func (clientOpts) IdleConnTimeout(timeout time.Duration) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.KeepAlive = true
+		opts.IdleConnTimeout = timeout
+		return nil
+	}
+}
+
+// DialTimeout bounds establishing a connection to the node.
+func (clientOpts) DialTimeout(timeout time.Duration) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.DialTimeout = timeout
+		return nil
+	}
+}
+
+// ResponseHeaderTimeout bounds the wait for the response headers once the
+// request, including its body, has been written.
+func (clientOpts) ResponseHeaderTimeout(timeout time.Duration) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.ResponseHeaderTimeout = timeout
+		return nil
+	}
+}
+
+// Proxy sets the function selecting a proxy for each request. By default the
+// proxy is taken from the environment.
+func (clientOpts) Proxy(proxy func(*http.Request) (*url.URL, error)) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.Proxy = proxy
+		return nil
+	}
+}
+
+// ProxyURL sends every request through the proxy at rawURL.
+func (clientOpts) ProxyURL(rawURL string) ClientOption {
+	return func(opts *ClientSettings) error {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		opts.Proxy = http.ProxyURL(u)
+		return nil
+	}
+}
+
+// NoProxy connects to the node directly, ignoring proxy environment
+// variables.
+func (clientOpts) NoProxy() ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.Proxy = nil
+		return nil
+	}
+}
+
+// RequestTimeout bounds every non-streaming call, from sending the request to
+// decoding its response. Unlike HttpClient.SetTimeout it does not cut off
+// streamed downloads such as Cat or Get, nor uploads such as Add, FilesWrite
+// or DagPut.
+func (clientOpts) RequestTimeout(timeout time.Duration) ClientOption {
+	return func(opts *ClientSettings) error {
+		opts.RequestTimeout = timeout
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+package options
+
+import "github.com/multiformats/go-multicodec"
+
+type DagGetSettings struct {
+	OutputCodec multicodec.Code
+}
+
+type DagGetOption func(opts *DagGetSettings) error
+
+func DagGetOptions(opts ...DagGetOption) (*DagGetSettings, error) {
+	options := &DagGetSettings{
+		OutputCodec: multicodec.DagJson,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+// parseCodec turns a multicodec name such as "dag-cbor" into its code.
+func parseCodec(name string) (multicodec.Code, error) {
+	var code multicodec.Code
+	if err := code.Set(name); err != nil {
+		return 0, err
+	}
+	return code, nil
+}
+
+// OutputCodec is the codec the node encodes the object with before sending
+// it: "dag-json" (default), "dag-cbor" or "raw".
+func (dagOpts) OutputCodec(codec string) DagGetOption {
+	return func(opts *DagGetSettings) error {
+		code, err := parseCodec(codec)
+		if err != nil {
+			return err
+		}
+		opts.OutputCodec = code
+		return nil
+	}
+}
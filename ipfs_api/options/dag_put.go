@@ -0,0 +1,82 @@
+package options
+
+import "github.com/multiformats/go-multicodec"
+
+type DagPutSettings struct {
+	StoreCodec    multicodec.Code
+	InputCodec    multicodec.Code
+	Hash          string
+	Pin           bool
+	AllowBigBlock bool
+}
+
+type DagPutOption func(opts *DagPutSettings) error
+
+func DagPutOptions(opts ...DagPutOption) (*DagPutSettings, error) {
+	options := &DagPutSettings{
+		StoreCodec:    multicodec.DagCbor,
+		InputCodec:    multicodec.DagJson,
+		Hash:          "sha2-256",
+		Pin:           false,
+		AllowBigBlock: false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+// StoreCodec is the codec the node stores the object with, "dag-cbor" by
+// default.
+func (dagOpts) StoreCodec(codec string) DagPutOption {
+	return func(opts *DagPutSettings) error {
+		code, err := parseCodec(codec)
+		if err != nil {
+			return err
+		}
+		opts.StoreCodec = code
+		return nil
+	}
+}
+
+// InputCodec is the codec the object is sent to the node with, "dag-json"
+// by default.
+func (dagOpts) InputCodec(codec string) DagPutOption {
+	return func(opts *DagPutSettings) error {
+		code, err := parseCodec(codec)
+		if err != nil {
+			return err
+		}
+		opts.InputCodec = code
+		return nil
+	}
+}
+
+// Hash is the multihash function used for the CID, "sha2-256" by default.
+func (dagOpts) Hash(hash string) DagPutOption {
+	return func(opts *DagPutSettings) error {
+		opts.Hash = hash
+		return nil
+	}
+}
+
+// Pin pins the stored object.
+func (dagOpts) Pin(pin bool) DagPutOption {
+	return func(opts *DagPutSettings) error {
+		opts.Pin = pin
+		return nil
+	}
+}
+
+// AllowBigBlock accepts objects larger than the 1MiB the network exchanges.
+func (dagOpts) AllowBigBlock(allow bool) DagPutOption {
+	return func(opts *DagPutSettings) error {
+		opts.AllowBigBlock = allow
+		return nil
+	}
+}
@@ -0,0 +1,31 @@
+package options
+
+type DagStatSettings struct {
+	Events chan<- interface{}
+}
+
+type DagStatOption func(opts *DagStatSettings) error
+
+func DagStatOptions(opts ...DagStatOption) (*DagStatSettings, error) {
+	options := &DagStatSettings{
+		Events: nil,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+// Events makes DagStat report the blocks counted so far to sink while the
+// node walks the DAG. The sink is not closed.
+func (dagOpts) Events(sink chan<- interface{}) DagStatOption {
+	return func(opts *DagStatSettings) error {
+		opts.Events = sink
+		return nil
+	}
+}
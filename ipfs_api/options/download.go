@@ -0,0 +1,97 @@
+package options
+
+import (
+	"errors"
+
+	caopts "github.com/urchinfs/go-urchin2-sdk/cid/options"
+)
+
+type DownloadSettings struct {
+	Events      chan<- interface{}
+	ReadAhead   int
+	Resume      bool
+	Concurrency int
+	Verify      bool
+	VerifyAdd   []caopts.UnixfsAddOption
+}
+
+type DownloadOption func(opts *DownloadSettings) error
+
+func DownloadOptions(opts ...DownloadOption) (*DownloadSettings, error) {
+	options := &DownloadSettings{
+		Events:      nil,
+		ReadAhead:   4 << 20,
+		Resume:      false,
+		Concurrency: 4,
+		Verify:      false,
+		VerifyAdd:   nil,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type downloadOpts struct{}
+
+var Download downloadOpts
+
+// Events makes downloads report their progress to sink. The sink is not
+// closed when the download ends.
+func (downloadOpts) Events(sink chan<- interface{}) DownloadOption {
+	return func(opts *DownloadSettings) error {
+		opts.Events = sink
+		return nil
+	}
+}
+
+// ReadAhead is the size of the ranged requests issued by CatReader, 4MiB by
+// default. Every request reads that much ahead of the caller.
+func (downloadOpts) ReadAhead(size int) DownloadOption {
+	return func(opts *DownloadSettings) error {
+		if size < 1 {
+			return errors.New("read ahead size must be positive")
+		}
+		opts.ReadAhead = size
+		return nil
+	}
+}
+
+// Resume makes Get download a tree file by file, skipping the files already
+// on disk, so an interrupted download can be picked up where it stopped.
+func (downloadOpts) Resume(resume bool) DownloadOption {
+	return func(opts *DownloadSettings) error {
+		opts.Resume = resume
+		return nil
+	}
+}
+
+// Concurrency is the number of files a resumable Get fetches at the same
+// time, 4 by default.
+func (downloadOpts) Concurrency(n int) DownloadOption {
+	return func(opts *DownloadSettings) error {
+		if n < 1 {
+			return errors.New("download concurrency must be at least 1")
+		}
+		opts.Concurrency = n
+		return nil
+	}
+}
+
+// Verify checks downloaded content against the requested CID. DagExport
+// re-hashes every block of the CAR file and checks the DAG is complete; Get
+// computes the UnixFS root of the extracted files again, with addOpts
+// describing how the content was added when it was not with the defaults of
+// its CID version.
+func (downloadOpts) Verify(addOpts ...caopts.UnixfsAddOption) DownloadOption {
+	return func(opts *DownloadSettings) error {
+		opts.Verify = true
+		opts.VerifyAdd = addOpts
+		return nil
+	}
+}
@@ -0,0 +1,165 @@
+package options
+
+import "errors"
+
+type FilesSettings struct {
+	Parents bool
+	Flush   bool
+
+	CidVersion   int
+	Hash         string
+	RawLeaves    bool
+	RawLeavesSet bool
+
+	Offset   int64
+	Count    int64
+	Create   bool
+	Truncate bool
+
+	Recursive bool
+	Force     bool
+	Long      bool
+}
+
+type FilesOption func(opts *FilesSettings) error
+
+func FilesOptions(opts ...FilesOption) (*FilesSettings, error) {
+	options := &FilesSettings{
+		Parents: false,
+		Flush:   true,
+
+		CidVersion:   -1,
+		Hash:         "",
+		RawLeaves:    false,
+		RawLeavesSet: false,
+
+		Offset:   0,
+		Count:    -1,
+		Create:   false,
+		Truncate: false,
+
+		Recursive: false,
+		Force:     false,
+		Long:      false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type filesOpts struct{}
+
+var Files filesOpts
+
+// Parents creates missing parent directories.
+func (filesOpts) Parents(parents bool) FilesOption {
+	return func(opts *FilesSettings) error {
+		opts.Parents = parents
+		return nil
+	}
+}
+
+// Flush writes the change through to the MFS root. It is on by default;
+// batches of changes may disable it and call FilesFlush once at the end.
+func (filesOpts) Flush(flush bool) FilesOption {
+	return func(opts *FilesSettings) error {
+		opts.Flush = flush
+		return nil
+	}
+}
+
+// CidVersion sets the CID version of new directories and written files.
+func (filesOpts) CidVersion(version int) FilesOption {
+	return func(opts *FilesSettings) error {
+		if version != 0 && version != 1 {
+			return errors.New("cid version must be 0 or 1")
+		}
+		opts.CidVersion = version
+		return nil
+	}
+}
+
+// Hash sets the hash function of new nodes, e.g. "sha2-256".
+func (filesOpts) Hash(hash string) FilesOption {
+	return func(opts *FilesSettings) error {
+		opts.Hash = hash
+		return nil
+	}
+}
+
+// RawLeaves stores the data of written files in raw blocks.
+func (filesOpts) RawLeaves(enable bool) FilesOption {
+	return func(opts *FilesSettings) error {
+		opts.RawLeaves = enable
+		opts.RawLeavesSet = true
+		return nil
+	}
+}
+
+// Offset is the byte offset to start reading or writing at.
+func (filesOpts) Offset(offset int64) FilesOption {
+	return func(opts *FilesSettings) error {
+		if offset < 0 {
+			return errors.New("offset must not be negative")
+		}
+		opts.Offset = offset
+		return nil
+	}
+}
+
+// Count is the maximum number of bytes to read or write.
+func (filesOpts) Count(count int64) FilesOption {
+	return func(opts *FilesSettings) error {
+		if count < 0 {
+			return errors.New("count must not be negative")
+		}
+		opts.Count = count
+		return nil
+	}
+}
+
+// Create creates the file if it does not exist.
+func (filesOpts) Create(create bool) FilesOption {
+	return func(opts *FilesSettings) error {
+		opts.Create = create
+		return nil
+	}
+}
+
+// Truncate truncates the file to zero length before writing.
+func (filesOpts) Truncate(truncate bool) FilesOption {
+	return func(opts *FilesSettings) error {
+		opts.Truncate = truncate
+		return nil
+	}
+}
+
+// Recursive removes directories with their content.
+func (filesOpts) Recursive(recursive bool) FilesOption {
+	return func(opts *FilesSettings) error {
+		opts.Recursive = recursive
+		return nil
+	}
+}
+
+// Force removes directories even when they are not empty.
+func (filesOpts) Force(force bool) FilesOption {
+	return func(opts *FilesSettings) error {
+		opts.Force = force
+		return nil
+	}
+}
+
+// Long includes the CID and size of every listed entry.
+func (filesOpts) Long(long bool) FilesOption {
+	return func(opts *FilesSettings) error {
+		opts.Long = long
+		return nil
+	}
+}
@@ -0,0 +1,172 @@
+package options
+
+import "fmt"
+
+type KeyGenSettings struct {
+	Type string
+	Size int
+}
+
+type KeyImportSettings struct {
+	Format          string
+	AllowAnyKeyType bool
+}
+
+type KeyExportSettings struct {
+	Format string
+}
+
+type KeyRenameSettings struct {
+	Force bool
+}
+
+type (
+	KeyGenOption    func(opts *KeyGenSettings) error
+	KeyImportOption func(opts *KeyImportSettings) error
+	KeyExportOption func(opts *KeyExportSettings) error
+	KeyRenameOption func(opts *KeyRenameSettings) error
+)
+
+const (
+	// KeyFormatLibp2p is the libp2p protobuf encoding of a private key.
+	KeyFormatLibp2p = "libp2p-protobuf-cleartext"
+	// KeyFormatPem is the PEM encoded PKCS8 form of a private key.
+	KeyFormatPem = "pem-pkcs8-cleartext"
+)
+
+func KeyGenOptions(opts ...KeyGenOption) (*KeyGenSettings, error) {
+	options := &KeyGenSettings{
+		Type: "ed25519",
+		Size: -1,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func KeyImportOptions(opts ...KeyImportOption) (*KeyImportSettings, error) {
+	options := &KeyImportSettings{
+		Format:          KeyFormatLibp2p,
+		AllowAnyKeyType: false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func KeyExportOptions(opts ...KeyExportOption) (*KeyExportSettings, error) {
+	options := &KeyExportSettings{
+		Format: KeyFormatLibp2p,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func KeyRenameOptions(opts ...KeyRenameOption) (*KeyRenameSettings, error) {
+	options := &KeyRenameSettings{
+		Force: false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func checkKeyFormat(format string) error {
+	switch format {
+	case KeyFormatLibp2p, KeyFormatPem:
+		return nil
+	default:
+		return fmt.Errorf("unknown key format %q", format)
+	}
+}
+
+type keyOpts struct{}
+
+var Key keyOpts
+
+// Type is the algorithm of the generated key: "ed25519" (default) or "rsa".
+func (keyOpts) Type(typ string) KeyGenOption {
+	return func(opts *KeyGenSettings) error {
+		switch typ {
+		case "ed25519", "rsa":
+		default:
+			return fmt.Errorf("unknown key type %q", typ)
+		}
+		opts.Type = typ
+		return nil
+	}
+}
+
+// Size is the size in bits of a generated rsa key.
+func (keyOpts) Size(size int) KeyGenOption {
+	return func(opts *KeyGenSettings) error {
+		opts.Size = size
+		return nil
+	}
+}
+
+// ImportFormat is the encoding of the imported key, KeyFormatLibp2p
+// (default) or KeyFormatPem.
+func (keyOpts) ImportFormat(format string) KeyImportOption {
+	return func(opts *KeyImportSettings) error {
+		if err := checkKeyFormat(format); err != nil {
+			return err
+		}
+		opts.Format = format
+		return nil
+	}
+}
+
+// AllowAnyKeyType accepts imported keys of types the node cannot use for
+// IPNS.
+func (keyOpts) AllowAnyKeyType(allow bool) KeyImportOption {
+	return func(opts *KeyImportSettings) error {
+		opts.AllowAnyKeyType = allow
+		return nil
+	}
+}
+
+// ExportFormat is the encoding of the exported key, KeyFormatLibp2p
+// (default) or KeyFormatPem.
+func (keyOpts) ExportFormat(format string) KeyExportOption {
+	return func(opts *KeyExportSettings) error {
+		if err := checkKeyFormat(format); err != nil {
+			return err
+		}
+		opts.Format = format
+		return nil
+	}
+}
+
+// Force overwrites an existing key with the renamed one.
+func (keyOpts) Force(force bool) KeyRenameOption {
+	return func(opts *KeyRenameSettings) error {
+		opts.Force = force
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+package options
+
+import "time"
+
+type NamePublishSettings struct {
+	Key          string
+	ValidTime    time.Duration
+	TTL          time.Duration
+	Resolve      bool
+	AllowOffline bool
+}
+
+type NameResolveSettings struct {
+	Recursive      bool
+	Cache          bool
+	DhtRecordCount uint
+	DhtTimeout     time.Duration
+}
+
+type (
+	NamePublishOption func(opts *NamePublishSettings) error
+	NameResolveOption func(opts *NameResolveSettings) error
+)
+
+func NamePublishOptions(opts ...NamePublishOption) (*NamePublishSettings, error) {
+	options := &NamePublishSettings{
+		Key:          "self",
+		ValidTime:    48 * time.Hour,
+		TTL:          0,
+		Resolve:      true,
+		AllowOffline: false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func NameResolveOptions(opts ...NameResolveOption) (*NameResolveSettings, error) {
+	options := &NameResolveSettings{
+		Recursive:      true,
+		Cache:          true,
+		DhtRecordCount: 0,
+		DhtTimeout:     0,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type nameOpts struct{}
+
+var Name nameOpts
+
+// Key is the name of the key, as listed by key/list, the record is signed
+// with. Defaults to "self", the node's own identity.
+func (nameOpts) Key(key string) NamePublishOption {
+	return func(opts *NamePublishSettings) error {
+		opts.Key = key
+		return nil
+	}
+}
+
+// ValidTime is how long the published record stays valid. Defaults to 48h.
+func (nameOpts) ValidTime(d time.Duration) NamePublishOption {
+	return func(opts *NamePublishSettings) error {
+		opts.ValidTime = d
+		return nil
+	}
+}
+
+// TTL hints resolvers how long they may cache the record before checking
+// for updates. Zero keeps the node default.
+func (nameOpts) TTL(ttl time.Duration) NamePublishOption {
+	return func(opts *NamePublishSettings) error {
+		opts.TTL = ttl
+		return nil
+	}
+}
+
+// Resolve checks that the published path resolves before publishing it.
+func (nameOpts) Resolve(resolve bool) NamePublishOption {
+	return func(opts *NamePublishSettings) error {
+		opts.Resolve = resolve
+		return nil
+	}
+}
+
+// AllowOffline lets an offline node store the record locally instead of
+// failing to broadcast it.
+func (nameOpts) AllowOffline(allow bool) NamePublishOption {
+	return func(opts *NamePublishSettings) error {
+		opts.AllowOffline = allow
+		return nil
+	}
+}
+
+// Recursive resolves until the result is no longer an IPNS name.
+func (nameOpts) Recursive(recursive bool) NameResolveOption {
+	return func(opts *NameResolveSettings) error {
+		opts.Recursive = recursive
+		return nil
+	}
+}
+
+// Cache allows the node to answer from its cache of resolved names.
+func (nameOpts) Cache(cache bool) NameResolveOption {
+	return func(opts *NameResolveSettings) error {
+		opts.Cache = cache
+		return nil
+	}
+}
+
+// DhtRecordCount is the number of records to collect from the DHT before
+// picking the best one. Zero keeps the node default.
+func (nameOpts) DhtRecordCount(count uint) NameResolveOption {
+	return func(opts *NameResolveSettings) error {
+		opts.DhtRecordCount = count
+		return nil
+	}
+}
+
+// DhtTimeout bounds the time spent collecting records from the DHT. Zero
+// keeps the node default.
+func (nameOpts) DhtTimeout(d time.Duration) NameResolveOption {
+	return func(opts *NameResolveSettings) error {
+		opts.DhtTimeout = d
+		return nil
+	}
+}
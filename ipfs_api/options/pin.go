@@ -0,0 +1,190 @@
+package options
+
+type PinAddSettings struct {
+	Recursive bool
+	Name      string
+	Events    chan<- interface{}
+}
+
+type PinRmSettings struct {
+	Recursive bool
+}
+
+type PinLsSettings struct {
+	Type  string
+	Names bool
+	Paths []string
+}
+
+type PinUpdateSettings struct {
+	Unpin bool
+}
+
+type PinVerifySettings struct {
+	Verbose bool
+}
+
+type (
+	PinAddOption    func(opts *PinAddSettings) error
+	PinRmOption     func(opts *PinRmSettings) error
+	PinLsOption     func(opts *PinLsSettings) error
+	PinUpdateOption func(opts *PinUpdateSettings) error
+	PinVerifyOption func(opts *PinVerifySettings) error
+)
+
+func PinAddOptions(opts ...PinAddOption) (*PinAddSettings, error) {
+	options := &PinAddSettings{
+		Recursive: true,
+		Name:      "",
+		Events:    nil,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func PinRmOptions(opts ...PinRmOption) (*PinRmSettings, error) {
+	options := &PinRmSettings{
+		Recursive: true,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func PinLsOptions(opts ...PinLsOption) (*PinLsSettings, error) {
+	options := &PinLsSettings{
+		Type:  "all",
+		Names: false,
+		Paths: nil,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func PinUpdateOptions(opts ...PinUpdateOption) (*PinUpdateSettings, error) {
+	options := &PinUpdateSettings{
+		Unpin: true,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func PinVerifyOptions(opts ...PinVerifyOption) (*PinVerifySettings, error) {
+	options := &PinVerifySettings{
+		Verbose: false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type pinOpts struct{}
+
+var Pin pinOpts
+
+// Recursive pins the whole DAG rather than only the root block.
+func (pinOpts) Recursive(recursive bool) PinAddOption {
+	return func(opts *PinAddSettings) error {
+		opts.Recursive = recursive
+		return nil
+	}
+}
+
+// Name attaches a name to the pin.
+func (pinOpts) Name(name string) PinAddOption {
+	return func(opts *PinAddSettings) error {
+		opts.Name = name
+		return nil
+	}
+}
+
+// Events makes PinAdd report the number of blocks fetched so far to sink.
+// The sink is not closed.
+func (pinOpts) Events(sink chan<- interface{}) PinAddOption {
+	return func(opts *PinAddSettings) error {
+		opts.Events = sink
+		return nil
+	}
+}
+
+// RmRecursive removes a recursive pin rather than a direct one.
+func (pinOpts) RmRecursive(recursive bool) PinRmOption {
+	return func(opts *PinRmSettings) error {
+		opts.Recursive = recursive
+		return nil
+	}
+}
+
+// Type filters listed pins by type: "direct", "indirect", "recursive" or
+// "all".
+func (pinOpts) Type(typ string) PinLsOption {
+	return func(opts *PinLsSettings) error {
+		opts.Type = typ
+		return nil
+	}
+}
+
+// Names includes the name of every pin in the listing.
+func (pinOpts) Names(names bool) PinLsOption {
+	return func(opts *PinLsSettings) error {
+		opts.Names = names
+		return nil
+	}
+}
+
+// Paths only lists the pins of the given paths.
+func (pinOpts) Paths(paths ...string) PinLsOption {
+	return func(opts *PinLsSettings) error {
+		opts.Paths = append(opts.Paths, paths...)
+		return nil
+	}
+}
+
+// Unpin removes the old pin once the new one is in place.
+func (pinOpts) Unpin(unpin bool) PinUpdateOption {
+	return func(opts *PinUpdateSettings) error {
+		opts.Unpin = unpin
+		return nil
+	}
+}
+
+// Verbose reports healthy pins too, not only the broken ones.
+func (pinOpts) Verbose(verbose bool) PinVerifyOption {
+	return func(opts *PinVerifySettings) error {
+		opts.Verbose = verbose
+		return nil
+	}
+}
@@ -0,0 +1,87 @@
+package options
+
+type PinRemoteSettings struct {
+	Service    string
+	Name       string
+	Background bool
+	Cids       []string
+	Status     []string
+	Force      bool
+}
+
+type PinRemoteOption func(opts *PinRemoteSettings) error
+
+func PinRemoteOptions(opts ...PinRemoteOption) (*PinRemoteSettings, error) {
+	options := &PinRemoteSettings{
+		Service:    "",
+		Name:       "",
+		Background: false,
+		Cids:       nil,
+		Status:     []string{"pinned"},
+		Force:      false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type pinRemoteOpts struct{}
+
+var PinRemote pinRemoteOpts
+
+// Service selects the remote pinning service, by the name it was registered
+// with on the node.
+func (pinRemoteOpts) Service(service string) PinRemoteOption {
+	return func(opts *PinRemoteSettings) error {
+		opts.Service = service
+		return nil
+	}
+}
+
+// Name names a new remote pin, or filters pins by name.
+func (pinRemoteOpts) Name(name string) PinRemoteOption {
+	return func(opts *PinRemoteSettings) error {
+		opts.Name = name
+		return nil
+	}
+}
+
+// Background returns as soon as the service has queued the pin instead of
+// waiting until it is pinned.
+func (pinRemoteOpts) Background(background bool) PinRemoteOption {
+	return func(opts *PinRemoteSettings) error {
+		opts.Background = background
+		return nil
+	}
+}
+
+// Cids filters pins by CID.
+func (pinRemoteOpts) Cids(cids ...string) PinRemoteOption {
+	return func(opts *PinRemoteSettings) error {
+		opts.Cids = append(opts.Cids, cids...)
+		return nil
+	}
+}
+
+// Status filters pins by status: "queued", "pinning", "pinned" or "failed".
+// Only pinned pins are matched by default.
+func (pinRemoteOpts) Status(status ...string) PinRemoteOption {
+	return func(opts *PinRemoteSettings) error {
+		opts.Status = status
+		return nil
+	}
+}
+
+// Force allows removing more than one pin matching the filters.
+func (pinRemoteOpts) Force(force bool) PinRemoteOption {
+	return func(opts *PinRemoteSettings) error {
+		opts.Force = force
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+package options
+
+import (
+	"errors"
+	"time"
+)
+
+type PoolSettings struct {
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	RefreshInterval     time.Duration
+	MaxAttempts         int
+
+	ClientOptions []ClientOption
+}
+
+type PoolOption func(opts *PoolSettings) error
+
+func PoolOptions(opts ...PoolOption) (*PoolSettings, error) {
+	options := &PoolSettings{
+		HealthCheckInterval: 30 * time.Second,
+		HealthCheckTimeout:  5 * time.Second,
+		RefreshInterval:     time.Minute,
+		MaxAttempts:         0,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type poolOpts struct{}
+
+var Pool poolOpts
+
+// HealthCheckInterval sets how often every node is probed with the
+// `version` command.
+func (poolOpts) HealthCheckInterval(interval time.Duration) PoolOption {
+	return func(opts *PoolSettings) error {
+		if interval <= 0 {
+			return errors.New("health check interval must be positive")
+		}
+		opts.HealthCheckInterval = interval
+		return nil
+	}
+}
+
+// HealthCheckTimeout bounds a single health probe.
+func (poolOpts) HealthCheckTimeout(timeout time.Duration) PoolOption {
+	return func(opts *PoolSettings) error {
+		if timeout <= 0 {
+			return errors.New("health check timeout must be positive")
+		}
+		opts.HealthCheckTimeout = timeout
+		return nil
+	}
+}
+
+// RefreshInterval sets how long the node list returned by the pool's
+// NodeProvider is trusted before it is asked again.
+func (poolOpts) RefreshInterval(interval time.Duration) PoolOption {
+	return func(opts *PoolSettings) error {
+		if interval <= 0 {
+			return errors.New("refresh interval must be positive")
+		}
+		opts.RefreshInterval = interval
+		return nil
+	}
+}
+
+// MaxAttempts limits how many nodes a read is tried on before giving up.
+// Zero means every node in the pool.
+func (poolOpts) MaxAttempts(attempts int) PoolOption {
+	return func(opts *PoolSettings) error {
+		if attempts < 0 {
+			return errors.New("max attempts must not be negative")
+		}
+		opts.MaxAttempts = attempts
+		return nil
+	}
+}
+
+// ClientOptions are applied to the client of every node in the pool, e.g. to
+// authenticate against all of them.
+func (poolOpts) ClientOptions(clientOpts ...ClientOption) PoolOption {
+	return func(opts *PoolSettings) error {
+		opts.ClientOptions = append(opts.ClientOptions, clientOpts...)
+		return nil
+	}
+}
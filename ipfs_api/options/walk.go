@@ -0,0 +1,80 @@
+package options
+
+import (
+	"errors"
+
+	sdkcid "github.com/urchinfs/go-urchin2-sdk/cid"
+)
+
+// WalkFilter decides whether an entry found by a walk is reported. Skipped
+// directories are not descended into.
+type WalkFilter func(path string, typ sdkcid.FileType) bool
+
+type WalkSettings struct {
+	MaxDepth    int
+	Filter      WalkFilter
+	Concurrency int
+	Size        bool
+}
+
+type WalkOption func(opts *WalkSettings) error
+
+func WalkOptions(opts ...WalkOption) (*WalkSettings, error) {
+	options := &WalkSettings{
+		MaxDepth:    0,
+		Filter:      nil,
+		Concurrency: 8,
+		Size:        true,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type walkOpts struct{}
+
+var Walk walkOpts
+
+// MaxDepth stops the walk depth levels below the root; 1 only lists the
+// root directory. 0, the default, and negative values walk the whole tree.
+func (walkOpts) MaxDepth(depth int) WalkOption {
+	return func(opts *WalkSettings) error {
+		opts.MaxDepth = depth
+		return nil
+	}
+}
+
+// Filter only reports the entries accepted by filter.
+func (walkOpts) Filter(filter WalkFilter) WalkOption {
+	return func(opts *WalkSettings) error {
+		opts.Filter = filter
+		return nil
+	}
+}
+
+// Concurrency is the number of directories listed at the same time, 8 by
+// default.
+func (walkOpts) Concurrency(n int) WalkOption {
+	return func(opts *WalkSettings) error {
+		if n < 1 {
+			return errors.New("walk concurrency must be at least 1")
+		}
+		opts.Concurrency = n
+		return nil
+	}
+}
+
+// Size asks the node for the size of files, which costs an extra block
+// read per file. Enabled by default.
+func (walkOpts) Size(size bool) WalkOption {
+	return func(opts *WalkSettings) error {
+		opts.Size = size
+		return nil
+	}
+}
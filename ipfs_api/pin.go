@@ -0,0 +1,264 @@
+package ipfs_api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// PinAddEvent reports how many nodes of the DAG have been fetched while
+// pinning it.
+type PinAddEvent struct {
+	Nodes int
+}
+
+// PinLsEntry is a single pin streamed by PinLs. Err is set, and the channel
+// closed afterwards, when listing fails.
+type PinLsEntry struct {
+	Cid  cid.Cid
+	Type string
+	Name string
+
+	Err error
+}
+
+// PinBadNode is a block found broken by PinVerify.
+type PinBadNode struct {
+	Cid cid.Cid
+	Err string
+}
+
+// PinVerifyResult is the verification result of a single recursive pin.
+type PinVerifyResult struct {
+	Cid      cid.Cid
+	Ok       bool
+	BadNodes []PinBadNode
+
+	Err error
+}
+
+func decodeCids(strs []string) ([]cid.Cid, error) {
+	out := make([]cid.Cid, 0, len(strs))
+	for _, s := range strs {
+		c, err := cid.Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// PinAdd pins path on the node and returns the pinned CIDs.
+func (h *HttpClient) PinAdd(ctx context.Context, path string, opts ...options.PinAddOption) ([]cid.Cid, error) {
+	settings, err := options.PinAddOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := h.Request("pin/add", path).
+		Option("recursive", settings.Recursive).
+		Option("progress", settings.Events != nil)
+	if settings.Name != "" {
+		rb.Option("name", settings.Name)
+	}
+
+	resp, err := rb.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	dec := json.NewDecoder(resp.Output)
+	for {
+		var out struct {
+			Pins     []string
+			Progress int
+		}
+		if err := dec.Decode(&out); err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if out.Pins != nil {
+			return decodeCids(out.Pins)
+		}
+
+		if settings.Events != nil {
+			select {
+			case settings.Events <- &PinAddEvent{Nodes: out.Progress}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+// PinRm removes the pin of path and returns the unpinned CIDs.
+func (h *HttpClient) PinRm(ctx context.Context, path string, opts ...options.PinRmOption) ([]cid.Cid, error) {
+	settings, err := options.PinRmOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct{ Pins []string }
+	err = h.Request("pin/rm", path).
+		Option("recursive", settings.Recursive).
+		Exec(ctx, &out)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCids(out.Pins)
+}
+
+// PinLs streams the pins held by the node. The channel is closed when the
+// listing ends or ctx is done.
+func (h *HttpClient) PinLs(ctx context.Context, opts ...options.PinLsOption) (<-chan PinLsEntry, error) {
+	settings, err := options.PinLsOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.Request("pin/ls", settings.Paths...).
+		Option("type", settings.Type).
+		Option("names", settings.Names).
+		Option("stream", true).
+		Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	out := make(chan PinLsEntry)
+	go func() {
+		defer close(out)
+		defer resp.Close()
+
+		dec := json.NewDecoder(resp.Output)
+		for {
+			var entry struct {
+				Cid  string
+				Type string
+				Name string
+			}
+			var res PinLsEntry
+			if err := dec.Decode(&entry); err != nil {
+				if err == io.EOF {
+					return
+				}
+				res.Err = err
+			} else if res.Cid, err = cid.Decode(entry.Cid); err != nil {
+				res.Err = err
+			} else {
+				res.Type = entry.Type
+				res.Name = entry.Name
+			}
+
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+			if res.Err != nil {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// PinUpdate moves a recursive pin from one path to another, fetching only the
+// blocks that differ.
+func (h *HttpClient) PinUpdate(ctx context.Context, from, to string, opts ...options.PinUpdateOption) error {
+	settings, err := options.PinUpdateOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	return h.Request("pin/update", from, to).
+		Option("unpin", settings.Unpin).
+		Exec(ctx, nil)
+}
+
+// PinVerify checks that every recursive pin is complete and readable, and
+// streams one result per pin. Only broken pins are reported unless
+// options.Pin.Verbose is set.
+func (h *HttpClient) PinVerify(ctx context.Context, opts ...options.PinVerifyOption) (<-chan PinVerifyResult, error) {
+	settings, err := options.PinVerifyOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.Request("pin/verify").
+		Option("verbose", settings.Verbose).
+		Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	out := make(chan PinVerifyResult)
+	go func() {
+		defer close(out)
+		defer resp.Close()
+
+		dec := json.NewDecoder(resp.Output)
+		for {
+			var entry struct {
+				Cid      string
+				Err      string
+				Ok       bool
+				BadNodes []struct {
+					Cid string
+					Err string
+				}
+			}
+			var res PinVerifyResult
+			err := dec.Decode(&entry)
+			if err == io.EOF {
+				return
+			}
+			if err == nil {
+				res.Cid, err = cid.Decode(entry.Cid)
+			}
+			if err != nil {
+				res.Err = err
+			} else {
+				res.Ok = entry.Ok
+				if entry.Err != "" {
+					res.Err = errors.New(entry.Err)
+				}
+				for _, bad := range entry.BadNodes {
+					c, _ := cid.Decode(bad.Cid)
+					res.BadNodes = append(res.BadNodes, PinBadNode{Cid: c, Err: bad.Err})
+				}
+			}
+
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
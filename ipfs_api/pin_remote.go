@@ -0,0 +1,177 @@
+package ipfs_api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// RemotePinService is a pinning service registered on the node. Stat is only
+// filled when requested.
+type RemotePinService struct {
+	Service     string
+	ApiEndpoint string
+	Stat        *RemotePinServiceStat `json:",omitempty"`
+}
+
+type RemotePinServiceStat struct {
+	Status   string
+	PinCount *struct {
+		Queued  int
+		Pinning int
+		Pinned  int
+		Failed  int
+	} `json:",omitempty"`
+}
+
+// RemotePin is a pin held by a remote pinning service. Err is set, and the
+// stream ends, when listing fails.
+type RemotePin struct {
+	Cid    cid.Cid
+	Name   string
+	Status string
+
+	Err error
+}
+
+type remotePinOutput struct {
+	Cid    string
+	Name   string
+	Status string
+}
+
+func (o *remotePinOutput) pin() (*RemotePin, error) {
+	c, err := cid.Decode(o.Cid)
+	if err != nil {
+		return nil, err
+	}
+	return &RemotePin{Cid: c, Name: o.Name, Status: o.Status}, nil
+}
+
+// PinRemoteServiceAdd registers a pinning service on the node under name.
+func (h *HttpClient) PinRemoteServiceAdd(ctx context.Context, name, endpoint, key string) error {
+	return h.Request("pin/remote/service/add", name, endpoint, key).Exec(ctx, nil)
+}
+
+// PinRemoteServiceLs lists the pinning services registered on the node,
+// optionally with their pin counts.
+func (h *HttpClient) PinRemoteServiceLs(ctx context.Context, stat bool) ([]RemotePinService, error) {
+	var out struct{ RemoteServices []RemotePinService }
+	err := h.Request("pin/remote/service/ls").
+		Option("stat", stat).
+		Exec(ctx, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out.RemoteServices, nil
+}
+
+// PinRemoteServiceRm unregisters a pinning service.
+func (h *HttpClient) PinRemoteServiceRm(ctx context.Context, name string) error {
+	return h.Request("pin/remote/service/rm", name).Exec(ctx, nil)
+}
+
+// PinRemoteAdd asks the service given with options.PinRemote.Service to pin
+// path, fetching the data from the node.
+func (h *HttpClient) PinRemoteAdd(ctx context.Context, path string, opts ...options.PinRemoteOption) (*RemotePin, error) {
+	settings, err := options.PinRemoteOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := h.Request("pin/remote/add", path).
+		Option("service", settings.Service).
+		Option("background", settings.Background)
+	if settings.Name != "" {
+		rb.Option("name", settings.Name)
+	}
+
+	var out remotePinOutput
+	if err := rb.Exec(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out.pin()
+}
+
+// PinRemoteLs streams the pins of a remote service matching the filters. The
+// channel is closed when the listing ends or ctx is done.
+func (h *HttpClient) PinRemoteLs(ctx context.Context, opts ...options.PinRemoteOption) (<-chan RemotePin, error) {
+	settings, err := options.PinRemoteOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := remoteFilter(h.Request("pin/remote/ls"), settings).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	out := make(chan RemotePin)
+	go func() {
+		defer close(out)
+		defer resp.Close()
+
+		dec := json.NewDecoder(resp.Output)
+		for {
+			var entry remotePinOutput
+			err := dec.Decode(&entry)
+			if err == io.EOF {
+				return
+			}
+
+			var res RemotePin
+			if err == nil {
+				var pin *RemotePin
+				if pin, err = entry.pin(); err == nil {
+					res = *pin
+				}
+			}
+			res.Err = err
+
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// PinRemoteRm removes the remote pins matching the filters. Removing more than
+// one pin requires options.PinRemote.Force.
+func (h *HttpClient) PinRemoteRm(ctx context.Context, opts ...options.PinRemoteOption) error {
+	settings, err := options.PinRemoteOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	return remoteFilter(h.Request("pin/remote/rm"), settings).
+		Option("force", settings.Force).
+		Exec(ctx, nil)
+}
+
+func remoteFilter(rb *RequestBuilder, settings *options.PinRemoteSettings) *RequestBuilder {
+	rb.Option("service", settings.Service)
+	if settings.Name != "" {
+		rb.Option("name", settings.Name)
+	}
+	if len(settings.Cids) > 0 {
+		rb.Option("cid", strings.Join(settings.Cids, ","))
+	}
+	if len(settings.Status) > 0 {
+		rb.Option("status", strings.Join(settings.Status, ","))
+	}
+	return rb
+}
@@ -0,0 +1,204 @@
+package ipfs_api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	caopts "github.com/urchinfs/go-urchin2-sdk/cid/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/testserver"
+)
+
+func pinLs(t *testing.T, c *HttpClient, opts ...options.PinLsOption) ([]PinLsEntry, error) {
+	t.Helper()
+	entries, err := c.PinLs(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []PinLsEntry
+	for e := range entries {
+		if e.Err != nil {
+			return out, e.Err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func TestPinAdd(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	root := addTree(t, c, caopts.Unixfs.Pin(false))
+
+	if pins, _ := pinLs(t, c); len(pins) != 0 {
+		t.Fatalf("pins after an unpinned add = %+v", pins)
+	}
+
+	events := make(chan interface{}, 16)
+	pinned, err := c.PinAdd(ctx, root.String(), options.Pin.Name("tree"), options.Pin.Events(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(events)
+	if len(pinned) != 1 || !pinned[0].Equals(root) {
+		t.Errorf("pinned = %v, want [%s]", pinned, root)
+	}
+
+	stat, err := c.DagStat(ctx, root.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last *PinAddEvent
+	for ev := range events {
+		last = ev.(*PinAddEvent)
+	}
+	if last == nil || last.Nodes != stat.UniqueBlocks {
+		t.Errorf("last event = %+v, want %d nodes", last, stat.UniqueBlocks)
+	}
+}
+
+func TestPinLs(t *testing.T) {
+	c, s := newTestClient(t)
+	ctx := context.Background()
+	root := addTree(t, c, caopts.Unixfs.Pin(false))
+	if _, err := c.PinAdd(ctx, root.String(), options.Pin.Name("tree")); err != nil {
+		t.Fatal(err)
+	}
+	file, err := c.AddBytes(ctx, "f", []byte("direct"), caopts.Unixfs.Wrap(false), caopts.Unixfs.Pin(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.PinAdd(ctx, file.Root.String(), options.Pin.Recursive(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	recursive, err := pinLs(t, c, options.Pin.Type("recursive"), options.Pin.Names(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recursive) != 1 || !recursive[0].Cid.Equals(root) || recursive[0].Name != "tree" {
+		t.Errorf("recursive pins = %+v, want %s named tree", recursive, root)
+	}
+
+	direct, err := pinLs(t, c, options.Pin.Type("direct"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(direct) != 1 || !direct[0].Cid.Equals(file.Root) || direct[0].Name != "" {
+		t.Errorf("direct pins = %+v, want %s without its name", direct, file.Root)
+	}
+
+	all, err := pinLs(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := c.DagStat(ctx, root.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != stat.UniqueBlocks+1 {
+		t.Errorf("%d pins, want the tree blocks and the direct pin", len(all))
+	}
+
+	only, err := pinLs(t, c, options.Pin.Paths(file.Root.String()))
+	if err != nil || len(only) != 1 || only[0].Type != "direct" {
+		t.Errorf("pins of %s = %+v, %v", file.Root, only, err)
+	}
+	if _, err := c.PinLs(ctx, options.Pin.Type("pinned")); err == nil {
+		t.Error("unknown pin type accepted")
+	}
+
+	// A failure after the first pins ends the stream with an error.
+	s.Inject("pin/ls", testserver.Fault{StreamError: "pin listing broke"})
+	got, err := pinLs(t, c)
+	if len(got) != len(all) || err == nil {
+		t.Errorf("broken listing = %d pins, %v; want %d pins then an error", len(got), err, len(all))
+	}
+}
+
+func TestPinUpdateRm(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	from, err := c.AddBytes(ctx, "v1", []byte("version 1"), caopts.Unixfs.Wrap(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := c.AddBytes(ctx, "v2", []byte("version 2"), caopts.Unixfs.Wrap(false), caopts.Unixfs.Pin(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.PinUpdate(ctx, from.Root.String(), to.Root.String()); err != nil {
+		t.Fatal(err)
+	}
+	pins, err := pinLs(t, c, options.Pin.Type("recursive"))
+	if err != nil || len(pins) != 1 || !pins[0].Cid.Equals(to.Root) {
+		t.Fatalf("pins after update = %+v, %v; want only %s", pins, err, to.Root)
+	}
+
+	removed, err := c.PinRm(ctx, to.Root.String())
+	if err != nil || len(removed) != 1 || !removed[0].Equals(to.Root) {
+		t.Fatalf("rm = %v, %v", removed, err)
+	}
+	var rpcErr *Error
+	if _, err := c.PinRm(ctx, to.Root.String()); !errors.As(err, &rpcErr) {
+		t.Errorf("second rm = %v, want an RPC error", err)
+	}
+}
+
+func TestPinVerify(t *testing.T) {
+	c, s := newTestClient(t)
+	ctx := context.Background()
+	big, err := c.AddBytes(ctx, "big", randomBytes(6, 600000), caopts.Unixfs.Wrap(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := c.AddBytes(ctx, "small", []byte("small"), caopts.Unixfs.Wrap(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verify := func(opts ...options.PinVerifyOption) map[cid.Cid]PinVerifyResult {
+		t.Helper()
+		results, err := c.PinVerify(ctx, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make(map[cid.Cid]PinVerifyResult)
+		for res := range results {
+			if res.Err != nil {
+				t.Fatal(res.Err)
+			}
+			out[res.Cid] = res
+		}
+		return out
+	}
+
+	if got := verify(); len(got) != 0 {
+		t.Errorf("healthy pins reported: %+v", got)
+	}
+	if got := verify(options.Pin.Verbose(true)); len(got) != 2 || !got[big.Root].Ok || !got[small.Root].Ok {
+		t.Errorf("verbose = %+v, want both pins ok", got)
+	}
+
+	// Lose a chunk of the big file.
+	indirect, err := pinLs(t, c, options.Pin.Type("indirect"))
+	if err != nil || len(indirect) == 0 {
+		t.Fatalf("indirect pins = %+v, %v", indirect, err)
+	}
+	lost := indirect[0].Cid
+	if err := s.Blockstore().DeleteBlock(ctx, lost); err != nil {
+		t.Fatal(err)
+	}
+
+	got := verify()
+	res, ok := got[big.Root]
+	if len(got) != 1 || !ok || res.Ok {
+		t.Fatalf("verify = %+v, want only %s broken", got, big.Root)
+	}
+	if len(res.BadNodes) != 1 || !res.BadNodes[0].Cid.Equals(lost) || res.BadNodes[0].Err == "" {
+		t.Errorf("bad nodes = %+v, want %s", res.BadNodes, lost)
+	}
+}
@@ -0,0 +1,514 @@
+package ipfs_api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs/boxo/files"
+	ipld "github.com/ipld/go-ipld-prime"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+	"github.com/urchinfs/go-urchin2-sdk/utils"
+)
+
+// NodeStatus is a snapshot of the health of a single pool node.
+type NodeStatus struct {
+	Addr      string
+	Healthy   bool
+	Version   string
+	LastCheck time.Time
+	LastErr   error
+}
+
+type poolNode struct {
+	addr   string
+	client *HttpClient
+
+	mu     sync.Mutex
+	status NodeStatus
+}
+
+func (n *poolNode) healthy() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.status.Healthy
+}
+
+func (n *poolNode) markHealthy(version string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.status.Healthy = true
+	n.status.Version = version
+	n.status.LastCheck = time.Now()
+	n.status.LastErr = nil
+}
+
+func (n *poolNode) markUnhealthy(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.status.Healthy = false
+	n.status.LastCheck = time.Now()
+	n.status.LastErr = err
+}
+
+func (n *poolNode) snapshot() NodeStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.status
+}
+
+// ClientPool spreads calls over several Kubo RPC endpoints. Nodes are probed
+// periodically and calls are routed round-robin to the healthy ones. Reads
+// fail over to another node on connection errors; writes are sent to a single
+// node and report which one received the data. The set of nodes comes from a
+// NodeProvider and is refreshed while the pool is running.
+type ClientPool struct {
+	settings *options.PoolSettings
+	provider NodeProvider
+
+	mu    sync.RWMutex
+	nodes []*poolNode
+	next  uint32
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewClientPool(addrs []string, opts ...options.PoolOption) (*ClientPool, error) {
+	return NewClientPoolFromProvider(context.Background(), NewStaticProvider(addrs...), opts...)
+}
+
+// NewClientPoolFromProvider builds a pool whose nodes are loaded from provider
+// and reloaded every RefreshInterval.
+func NewClientPoolFromProvider(ctx context.Context, provider NodeProvider, opts ...options.PoolOption) (*ClientPool, error) {
+	settings, err := options.PoolOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := options.ClientOptions(settings.ClientOptions...); err != nil {
+		return nil, err
+	}
+
+	addrs, err := provider.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, utils.ErrNoNodes
+	}
+
+	p := &ClientPool{
+		settings: settings,
+		provider: provider,
+		done:     make(chan struct{}),
+	}
+	p.setNodes(addrs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.healthLoop(ctx)
+
+	return p, nil
+}
+
+func newPoolNode(addr string, opts ...options.ClientOption) (*poolNode, error) {
+	client, err := NewClient(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &poolNode{
+		addr:   addr,
+		client: client,
+		// Nodes are optimistically healthy until the first probe says otherwise.
+		status: NodeStatus{Addr: addr, Healthy: true},
+	}, nil
+}
+
+// setNodes replaces the node set with addrs, keeping the clients and health
+// status of nodes that are still listed.
+func (p *ClientPool) setNodes(addrs []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing := make(map[string]*poolNode, len(p.nodes))
+	for _, n := range p.nodes {
+		existing[n.addr] = n
+	}
+
+	nodes := make([]*poolNode, 0, len(addrs))
+	seen := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+
+		if n, ok := existing[addr]; ok {
+			nodes = append(nodes, n)
+			continue
+		}
+		n, err := newPoolNode(addr, p.settings.ClientOptions...)
+		if err != nil {
+			log.Errorf("create client for ipfs node %s err:%v", addr, err)
+			continue
+		}
+		log.Infof("ipfs node %s joined the pool", addr)
+		nodes = append(nodes, n)
+	}
+	for addr := range existing {
+		if !seen[addr] {
+			log.Infof("ipfs node %s left the pool", addr)
+		}
+	}
+
+	p.nodes = nodes
+}
+
+// Refresh asks the provider for the current node list and applies it. An
+// empty list is ignored so a misbehaving provider cannot drain the pool.
+func (p *ClientPool) Refresh(ctx context.Context) error {
+	addrs, err := p.provider.Nodes(ctx)
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return utils.ErrNoNodes
+	}
+
+	p.setNodes(addrs)
+	return nil
+}
+
+// Close stops the health checking loop.
+func (p *ClientPool) Close() error {
+	p.cancel()
+	<-p.done
+	return nil
+}
+
+// Nodes returns the current status of every node in the pool.
+func (p *ClientPool) Nodes() []NodeStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	out := make([]NodeStatus, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		out = append(out, n.snapshot())
+	}
+	return out
+}
+
+// CheckHealth probes every node once and updates its status.
+func (p *ClientPool) CheckHealth(ctx context.Context) {
+	p.mu.RLock()
+	nodes := append([]*poolNode(nil), p.nodes...)
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n *poolNode) {
+			defer wg.Done()
+			p.probe(ctx, n)
+		}(n)
+	}
+	wg.Wait()
+}
+
+func (p *ClientPool) probe(ctx context.Context, n *poolNode) {
+	ctx, cancel := context.WithTimeout(ctx, p.settings.HealthCheckTimeout)
+	defer cancel()
+
+	version, _, err := n.client.Version(ctx)
+	if err != nil {
+		log.Warnf("ipfs node %s failed health check: %v", n.addr, err)
+		if nodeDown(err) {
+			n.markUnhealthy(err)
+		}
+		return
+	}
+	n.markHealthy(version)
+}
+
+func (p *ClientPool) healthLoop(ctx context.Context) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.settings.HealthCheckInterval)
+	defer ticker.Stop()
+	refresh := time.NewTicker(p.settings.RefreshInterval)
+	defer refresh.Stop()
+
+	p.CheckHealth(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.CheckHealth(ctx)
+		case <-refresh.C:
+			if err := p.Refresh(ctx); err != nil {
+				log.Warnf("refresh ipfs node list err:%v", err)
+				continue
+			}
+			p.CheckHealth(ctx)
+		}
+	}
+}
+
+// pick returns the next node to use, skipping the ones in tried. Healthy nodes
+// are preferred; when none is left the unhealthy ones are tried as a last
+// resort, since the last probe may be stale.
+func (p *ClientPool) pick(tried map[*poolNode]bool) *poolNode {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	count := len(p.nodes)
+	if count == 0 {
+		return nil
+	}
+	start := int(atomic.AddUint32(&p.next, 1))
+
+	var fallback *poolNode
+	for i := 0; i < count; i++ {
+		n := p.nodes[(start+i)%count]
+		if tried[n] {
+			continue
+		}
+		if n.healthy() {
+			return n
+		}
+		if fallback == nil {
+			fallback = n
+		}
+	}
+	return fallback
+}
+
+// Client returns a client for the next healthy node.
+func (p *ClientPool) Client() (*HttpClient, string, error) {
+	n := p.pick(nil)
+	if n == nil {
+		return nil, "", utils.ErrNoNodes
+	}
+	return n.client, n.addr, nil
+}
+
+// Do runs fn against a node and retries it on the next one whenever fn fails
+// with a connection error. Only idempotent operations should go through Do.
+func (p *ClientPool) Do(ctx context.Context, fn func(*HttpClient) error) error {
+	tried := make(map[*poolNode]bool)
+	var lastErr error
+	for {
+		if p.settings.MaxAttempts > 0 && len(tried) >= p.settings.MaxAttempts {
+			break
+		}
+		n := p.pick(tried)
+		if n == nil {
+			break
+		}
+		tried[n] = true
+
+		err := fn(n.client)
+		if err == nil {
+			return nil
+		}
+		if !p.failover(ctx, n, err) {
+			return err
+		}
+		log.Warnf("ipfs node %s failed, trying next node: %v", n.addr, err)
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		return utils.ErrNoNodes
+	}
+	return lastErr
+}
+
+// write runs fn against exactly one node and returns that node's address.
+func (p *ClientPool) write(ctx context.Context, fn func(*HttpClient) error) (string, error) {
+	n := p.pick(nil)
+	if n == nil {
+		return "", utils.ErrNoNodes
+	}
+
+	err := fn(n.client)
+	if err != nil {
+		p.failover(ctx, n, err)
+	}
+	return n.addr, err
+}
+
+// failover reports whether err is a failure of the node worth trying on
+// another one, and marks the node unhealthy if so. Errors of the call itself,
+// such as local files or refused arguments, are left to the caller.
+func (p *ClientPool) failover(ctx context.Context, n *poolNode, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if !nodeDown(err) {
+		return false
+	}
+	n.markUnhealthy(err)
+	return true
+}
+
+// nodeDown tells whether err shows the node cannot serve requests: it could
+// not be reached, or it reports being offline.
+func nodeDown(err error) bool {
+	var transportErr *TransportError
+	return errors.As(err, &transportErr) || errors.Is(err, utils.ErrOffline)
+}
+
+func (p *ClientPool) Version(ctx context.Context) (version string, commit string, err error) {
+	err = p.Do(ctx, func(c *HttpClient) error {
+		version, commit, err = c.Version(ctx)
+		return err
+	})
+	return version, commit, err
+}
+
+func (p *ClientPool) Cat(ctx context.Context, path string, opts ...options.DownloadOption) (rc io.ReadCloser, err error) {
+	err = p.Do(ctx, func(c *HttpClient) error {
+		rc, err = c.Cat(ctx, path, opts...)
+		return err
+	})
+	return rc, err
+}
+
+// CatRange streams a range of the file at path from the first healthy node.
+func (p *ClientPool) CatRange(ctx context.Context, path string, offset, length int64) (rc io.ReadCloser, err error) {
+	err = p.Do(ctx, func(c *HttpClient) error {
+		rc, err = c.CatRange(ctx, path, offset, length)
+		return err
+	})
+	return rc, err
+}
+
+// CatReader opens the file at path for random access. Every ranged request,
+// including the ones resuming a broken read, may be served by another node.
+func (p *ClientPool) CatReader(ctx context.Context, path string, opts ...options.DownloadOption) (io.ReadSeekCloser, error) {
+	return newCatReader(ctx, p, path, opts...)
+}
+
+func (p *ClientPool) FilesStat(ctx context.Context, path string) (stat *MfsStat, err error) {
+	err = p.Do(ctx, func(c *HttpClient) error {
+		stat, err = c.FilesStat(ctx, path)
+		return err
+	})
+	return stat, err
+}
+
+func (p *ClientPool) List(ctx context.Context, path string) (links []*LsLink, err error) {
+	err = p.Do(ctx, func(c *HttpClient) error {
+		links, err = c.List(ctx, path)
+		return err
+	})
+	return links, err
+}
+
+func (p *ClientPool) Get(ctx context.Context, hash, outDir string, opts ...options.DownloadOption) error {
+	return p.Do(ctx, func(c *HttpClient) error {
+		return c.Get(ctx, hash, outDir, opts...)
+	})
+}
+
+func (p *ClientPool) DagGet(ctx context.Context, ref string, opts ...options.DagGetOption) (node ipld.Node, err error) {
+	err = p.Do(ctx, func(c *HttpClient) error {
+		node, err = c.DagGet(ctx, ref, opts...)
+		return err
+	})
+	return node, err
+}
+
+func (p *ClientPool) DagGetJSON(ctx context.Context, ref string, out interface{}) error {
+	return p.Do(ctx, func(c *HttpClient) error {
+		return c.DagGetJSON(ctx, ref, out)
+	})
+}
+
+func (p *ClientPool) DagExport(ctx context.Context, hash, outputFile string, opts ...options.DownloadOption) error {
+	return p.Do(ctx, func(c *HttpClient) error {
+		return c.DagExport(ctx, hash, outputFile, opts...)
+	})
+}
+
+// Add uploads inputFile to a single node. The address of the node holding
+// the data is reported in the result's Node field.
+func (p *ClientPool) Add(ctx context.Context, inputFile string, options ...AddOpts) (result *AddResult, err error) {
+	node, err := p.write(ctx, func(c *HttpClient) error {
+		result, err = c.Add(ctx, inputFile, options...)
+		return err
+	})
+	if result != nil {
+		result.Node = node
+	}
+	return result, err
+}
+
+func (p *ClientPool) AddDir(ctx context.Context, dir string, options ...AddOpts) (result *AddResult, err error) {
+	node, err := p.write(ctx, func(c *HttpClient) error {
+		result, err = c.AddDir(ctx, dir, options...)
+		return err
+	})
+	if result != nil {
+		result.Node = node
+	}
+	return result, err
+}
+
+func (p *ClientPool) AddReader(ctx context.Context, name string, r io.Reader, options ...AddOpts) (result *AddResult, err error) {
+	node, err := p.write(ctx, func(c *HttpClient) error {
+		result, err = c.AddReader(ctx, name, r, options...)
+		return err
+	})
+	if result != nil {
+		result.Node = node
+	}
+	return result, err
+}
+
+func (p *ClientPool) AddBytes(ctx context.Context, name string, data []byte, options ...AddOpts) (result *AddResult, err error) {
+	node, err := p.write(ctx, func(c *HttpClient) error {
+		result, err = c.AddBytes(ctx, name, data, options...)
+		return err
+	})
+	if result != nil {
+		result.Node = node
+	}
+	return result, err
+}
+
+func (p *ClientPool) AddNode(ctx context.Context, name string, fnode files.Node, options ...AddOpts) (result *AddResult, err error) {
+	node, err := p.write(ctx, func(c *HttpClient) error {
+		result, err = c.AddNode(ctx, name, fnode, options...)
+		return err
+	})
+	if result != nil {
+		result.Node = node
+	}
+	return result, err
+}
+
+func (p *ClientPool) DagImport(ctx context.Context, input string, silent, stats bool) (out *DagImportOutput, node string, err error) {
+	node, err = p.write(ctx, func(c *HttpClient) error {
+		out, err = c.DagImport(ctx, input, silent, stats)
+		return err
+	})
+	return out, node, err
+}
+
+func (p *ClientPool) DagImportWithOpts(ctx context.Context, data interface{}, opts ...options.DagImportOption) (out *DagImportOutput, node string, err error) {
+	node, err = p.write(ctx, func(c *HttpClient) error {
+		out, err = c.DagImportWithOpts(ctx, data, opts...)
+		return err
+	})
+	return out, node, err
+}
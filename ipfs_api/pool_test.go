@@ -0,0 +1,144 @@
+package ipfs_api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"testing"
+
+	caopts "github.com/urchinfs/go-urchin2-sdk/cid/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/testserver"
+	"github.com/urchinfs/go-urchin2-sdk/utils"
+)
+
+func newTestPool(t *testing.T, addrs ...string) *ClientPool {
+	t.Helper()
+	p, err := NewClientPool(addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func nodeStatus(p *ClientPool, addr string) NodeStatus {
+	for _, n := range p.Nodes() {
+		if n.Addr == addr {
+			return n
+		}
+	}
+	return NodeStatus{}
+}
+
+func TestPoolFailoverOnTransportError(t *testing.T) {
+	live, _ := newTestClient(t)
+	res, err := live.AddBytes(context.Background(), "f", []byte("pooled"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := testserver.New()
+	dead.Close()
+
+	p := newTestPool(t, dead.URL(), live.url)
+	// Round-robin starts calls on both nodes; all end on the live one.
+	for i := 0; i < 2; i++ {
+		rc, err := p.Cat(context.Background(), res.Root.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil || string(data) != "pooled" {
+			t.Fatalf("cat = %q, %v", data, err)
+		}
+	}
+
+	status := nodeStatus(p, dead.URL())
+	var transportErr *TransportError
+	if status.Healthy || !errors.As(status.LastErr, &transportErr) {
+		t.Errorf("dead node status = %+v, want unhealthy after a transport error", status)
+	}
+	if !nodeStatus(p, live.url).Healthy {
+		t.Error("live node marked unhealthy")
+	}
+}
+
+func TestPoolFailoverOnOffline(t *testing.T) {
+	offline, a := newTestClient(t)
+	online, b := newTestClient(t)
+	res, err := online.AddBytes(context.Background(), "f", []byte("pooled"), Pin(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Inject("version", testserver.Fault{Status: http.StatusInternalServerError, Message: "this action must be run in online mode"})
+	a.Inject("cat", testserver.Fault{Status: http.StatusInternalServerError, Message: "this action must be run in online mode"})
+
+	p := newTestPool(t, offline.url, online.url)
+	for i := 0; i < 2; i++ {
+		rc, err := p.Cat(context.Background(), res.Root.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc.Close()
+	}
+	if b.Requests("cat") != 2 {
+		t.Errorf("online node served %d of 2 calls", b.Requests("cat"))
+	}
+	if status := nodeStatus(p, offline.url); status.Healthy || !errors.Is(status.LastErr, utils.ErrOffline) {
+		t.Errorf("offline node status = %+v, want unhealthy", status)
+	}
+}
+
+func TestPoolNoFailoverOnLocalError(t *testing.T) {
+	c1, s1 := newTestClient(t)
+	c2, s2 := newTestClient(t)
+	p := newTestPool(t, c1.url, c2.url)
+	ctx := context.Background()
+
+	// The node reports the content missing; another node would not help.
+	res, err := c1.AddBytes(ctx, "f", []byte("not stored"), Pin(false), caopts.Unixfs.HashOnly(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Cat(ctx, res.Root.String()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("cat = %v, want ErrNotFound", err)
+	}
+	if n := s1.Requests("cat") + s2.Requests("cat"); n != 1 {
+		t.Errorf("cat sent to %d nodes", n)
+	}
+
+	// Errors of the call itself never reach a node.
+	calls := 0
+	err = p.Do(ctx, func(c *HttpClient) error {
+		calls++
+		return c.Get(ctx, res.Root.String(), "/does/not/exist")
+	})
+	if !errors.Is(err, fs.ErrNotExist) || calls != 1 {
+		t.Errorf("get = %v after %d calls, want ErrNotExist after 1", err, calls)
+	}
+	if _, err := p.Add(ctx, "/does/not/exist"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("add = %v, want ErrNotExist", err)
+	}
+
+	for _, status := range p.Nodes() {
+		if !status.Healthy {
+			t.Errorf("node %s marked unhealthy by %v", status.Addr, status.LastErr)
+		}
+	}
+}
+
+func TestPoolWriteMarksDeadNode(t *testing.T) {
+	dead := testserver.New()
+	dead.Close()
+	p := newTestPool(t, dead.URL())
+
+	res, err := p.AddBytes(context.Background(), "f", []byte("lost"))
+	if err == nil || res != nil {
+		t.Fatalf("add = %v, %v, want an error", res, err)
+	}
+	if status := nodeStatus(p, dead.URL()); status.Healthy {
+		t.Error("dead node still healthy after a failed write")
+	}
+}
@@ -0,0 +1,194 @@
+package ipfs_api
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ipfs/go-cid"
+	sdkcid "github.com/urchinfs/go-urchin2-sdk/cid"
+)
+
+// AddEvent reports the progress of an upload. It mirrors car.ImportEvent so
+// online and offline imports can be tracked the same way: events with an
+// undefined CID only report Bytes acknowledged by the node so far, the others
+// announce a finished file or directory. Sent is the number of request body
+// bytes written to the node when the event was received.
+type AddEvent struct {
+	Name  string
+	CID   cid.Cid
+	Bytes int64
+	Size  string
+	Sent  int64
+}
+
+// countingReader counts the bytes read through it into n.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Boundary exposes the multipart boundary of the wrapped reader, so counting
+// does not hide the content type of an upload.
+func (c *countingReader) Boundary() string {
+	if b, ok := c.r.(multipartBody); ok {
+		return b.Boundary()
+	}
+	return ""
+}
+
+// progressStep is how many received bytes are batched into one download
+// event.
+const progressStep = 1 << 20
+
+// DownloadEvent reports the progress of Get, Cat or DagExport. Path is the
+// requested path, or for Get the entry currently being extracted. Total is
+// the expected number of bytes as reported by the node before the transfer
+// started, or zero when unknown; for Get it is an estimate since the archive
+// adds headers. The last event of a successful download has Done set.
+type DownloadEvent struct {
+	Path  string
+	Bytes int64
+	Files int
+	Total int64
+	Done  bool
+}
+
+type downloadProgress struct {
+	ctx    context.Context
+	events chan<- interface{}
+
+	ev       DownloadEvent
+	reported int64
+}
+
+func newDownloadProgress(ctx context.Context, events chan<- interface{}, path string, total int64) *downloadProgress {
+	return &downloadProgress{
+		ctx:    ctx,
+		events: events,
+		ev: DownloadEvent{
+			Path:  path,
+			Total: total,
+		},
+	}
+}
+
+func (p *downloadProgress) emit() error {
+	ev := p.ev
+	p.reported = ev.Bytes
+
+	select {
+	case p.events <- &ev:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+func (p *downloadProgress) received(n int) error {
+	p.ev.Bytes += int64(n)
+	if p.ev.Bytes-p.reported >= progressStep {
+		return p.emit()
+	}
+	return nil
+}
+
+func (p *downloadProgress) done() error {
+	p.ev.Done = true
+	return p.emit()
+}
+
+// watchTar follows a tar stream as it is extracted elsewhere and reports each
+// entry. It always consumes r to the end so the writer never blocks.
+func (p *downloadProgress) watchTar(r io.Reader) error {
+	defer io.Copy(io.Discard, r)
+
+	tr := tar.NewReader(&progressReader{r: io.NopCloser(r), p: p})
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		p.ev.Path = hdr.Name
+		if hdr.Typeflag != tar.TypeDir {
+			p.ev.Files++
+		}
+		if err := p.emit(); err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// progressReader reports the bytes read through it. When final is set it also
+// sends the last event once the stream ends.
+type progressReader struct {
+	r     io.ReadCloser
+	p     *downloadProgress
+	final bool
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if perr := r.p.received(n); perr != nil && err == nil {
+		err = perr
+	}
+	if err == io.EOF && r.final {
+		r.final = false
+		if perr := r.p.done(); perr != nil {
+			err = perr
+		}
+	}
+	return n, err
+}
+
+func (r *progressReader) Close() error {
+	return r.r.Close()
+}
+
+// ipfsPath turns a bare CID into an /ipfs/ path.
+func ipfsPath(p string) string {
+	if strings.HasPrefix(p, "/") {
+		return p
+	}
+	return "/ipfs/" + p
+}
+
+// expectedFileSize asks the node for the size of the content at p. Errors are
+// not fatal for a download, so zero is returned when the size is unknown.
+func (h *HttpClient) expectedFileSize(ctx context.Context, p string) int64 {
+	stat, err := h.FilesStat(ctx, ipfsPath(p))
+	if err != nil {
+		log.Debugf("files/stat %s err:%v", p, err)
+		return 0
+	}
+	if stat.Type == sdkcid.TDirectory {
+		return int64(stat.CumulativeSize)
+	}
+	return int64(stat.Size)
+}
+
+// expectedDagSize asks the node for the total block size of the DAG under
+// hash, which is close to the size of its CAR export.
+func (h *HttpClient) expectedDagSize(ctx context.Context, hash string) int64 {
+	stat, err := h.DagStat(ctx, hash)
+	if err != nil {
+		log.Debugf("dag/stat %s err:%v", hash, err)
+		return 0
+	}
+	return int64(stat.TotalSize)
+}
@@ -0,0 +1,193 @@
+package ipfs_api
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	caopts "github.com/urchinfs/go-urchin2-sdk/cid/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/testserver"
+)
+
+// bigFileSize spans several progress steps of the node and of downloads.
+const bigFileSize = 3<<20 + 12345
+
+func downloadEvents(t *testing.T, events chan interface{}) []*DownloadEvent {
+	t.Helper()
+	close(events)
+	var out []*DownloadEvent
+	for ev := range events {
+		out = append(out, ev.(*DownloadEvent))
+	}
+	if len(out) == 0 {
+		t.Fatal("no download events")
+	}
+	for i := 1; i < len(out); i++ {
+		if out[i].Bytes < out[i-1].Bytes {
+			t.Errorf("event %d: bytes went down from %d to %d", i, out[i-1].Bytes, out[i].Bytes)
+		}
+	}
+	for _, ev := range out[:len(out)-1] {
+		if ev.Done {
+			t.Errorf("%+v done before the last event", ev)
+		}
+	}
+	return out
+}
+
+// smallBufferListener shrinks the receive buffer of accepted connections.
+type smallBufferListener struct {
+	net.Listener
+}
+
+func (l smallBufferListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if tc, ok := conn.(*net.TCPConn); ok {
+		tc.SetReadBuffer(32 << 10)
+	}
+	return conn, err
+}
+
+// newSlowUploadClient connects to a test node through small socket buffers,
+// so little of an upload is in flight and the bytes sent follow the node
+// reading them, as they would on a real network.
+func newSlowUploadClient(t *testing.T) *HttpClient {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(testserver.NewUnstarted())
+	ts.Listener = smallBufferListener{ts.Listener}
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	var d net.Dialer
+	hc := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := d.DialContext(ctx, network, addr)
+			if tc, ok := conn.(*net.TCPConn); ok {
+				tc.SetWriteBuffer(32 << 10)
+			}
+			return conn, err
+		},
+	}}
+	c, err := NewClient(ts.URL, options.Client.HTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestAddEvents(t *testing.T) {
+	c := newSlowUploadClient(t)
+	file := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(file, randomBytes(7, bigFileSize), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan interface{}, 64)
+	res, err := c.Add(context.Background(), file, caopts.Unixfs.Wrap(false), caopts.Unixfs.Events(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(events)
+
+	var progress []*AddEvent
+	var last *AddEvent
+	for ev := range events {
+		e := ev.(*AddEvent)
+		if last != nil && e.Sent < last.Sent {
+			t.Errorf("sent went down from %d to %d", last.Sent, e.Sent)
+		}
+		if !e.CID.Defined() {
+			if e.Sent < e.Bytes {
+				t.Errorf("node acknowledged %d bytes when %d were sent", e.Bytes, e.Sent)
+			}
+			progress = append(progress, e)
+		}
+		last = e
+	}
+
+	if len(progress) < 2 {
+		t.Fatalf("%d progress events, want several for %d bytes", len(progress), bigFileSize)
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i].Bytes <= progress[i-1].Bytes {
+			t.Errorf("progress %d: bytes %d after %d", i, progress[i].Bytes, progress[i-1].Bytes)
+		}
+	}
+	if n := progress[len(progress)-1].Bytes; n != bigFileSize {
+		t.Errorf("progress ends at %d bytes, want %d", n, bigFileSize)
+	}
+	if progress[0].Sent >= last.Sent {
+		t.Errorf("sent stayed at %d", last.Sent)
+	}
+	if !last.CID.Equals(res.Root) || last.Name != "big.bin" {
+		t.Errorf("last event = %+v, want big.bin added as %s", last, res.Root)
+	}
+}
+
+func TestCatEvents(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	added, err := c.AddBytes(ctx, "big.bin", randomBytes(8, bigFileSize), caopts.Unixfs.Wrap(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan interface{}, 64)
+	r, err := c.Cat(ctx, added.Root.String(), options.Download.Events(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := io.Copy(io.Discard, r)
+	r.Close()
+	if err != nil || n != bigFileSize {
+		t.Fatalf("read %d bytes, %v", n, err)
+	}
+
+	got := downloadEvents(t, events)
+	if len(got) < 2 {
+		t.Errorf("%d events, want progress before the end", len(got))
+	}
+	last := got[len(got)-1]
+	if !last.Done || last.Bytes != bigFileSize || last.Total != bigFileSize || last.Path != added.Root.String() {
+		t.Errorf("last event = %+v, want done at %d bytes", last, bigFileSize)
+	}
+}
+
+func TestDagExportEvents(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	added, err := c.AddBytes(ctx, "big.bin", randomBytes(9, bigFileSize), caopts.Unixfs.Wrap(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := c.DagStat(ctx, added.Root.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan interface{}, 64)
+	out := filepath.Join(t.TempDir(), "big.car")
+	if err := c.DagExport(ctx, added.Root.String(), out, options.Download.Events(events)); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := downloadEvents(t, events)
+	last := got[len(got)-1]
+	if !last.Done || last.Bytes != fi.Size() {
+		t.Errorf("last event = %+v, want done at the %d bytes of the CAR", last, fi.Size())
+	}
+	// The CAR adds a header and block framing to the DAG.
+	if last.Total != int64(stat.TotalSize) || last.Total > fi.Size() {
+		t.Errorf("total = %d, want the DAG size %d", last.Total, stat.TotalSize)
+	}
+}
@@ -0,0 +1,159 @@
+package ipfs_api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	gohttp "net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NodeProvider supplies the list of Kubo RPC endpoints a ClientPool talks to.
+// Nodes is called once when the pool is created and then on every refresh, so
+// implementations may return a different list over time.
+type NodeProvider interface {
+	Nodes(ctx context.Context) ([]string, error)
+}
+
+// nodeList is the document understood by the file and HTTP providers. Both a
+// bare list of addresses and an object with a "nodes" field are accepted.
+type nodeList struct {
+	Nodes []string `json:"nodes" yaml:"nodes"`
+}
+
+func parseNodeList(data []byte, yamlFormat bool) ([]string, error) {
+	unmarshal := json.Unmarshal
+	if yamlFormat {
+		unmarshal = yaml.Unmarshal
+	}
+
+	var list []string
+	if err := unmarshal(data, &list); err == nil {
+		return list, nil
+	}
+
+	var doc nodeList
+	if err := unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse node list: %w", err)
+	}
+	return doc.Nodes, nil
+}
+
+type staticProvider struct {
+	addrs []string
+}
+
+// NewStaticProvider returns a provider that always yields addrs.
+func NewStaticProvider(addrs ...string) NodeProvider {
+	return &staticProvider{addrs: append([]string(nil), addrs...)}
+}
+
+func (p *staticProvider) Nodes(_ context.Context) ([]string, error) {
+	return append([]string(nil), p.addrs...), nil
+}
+
+// FileProvider reads the node list from a JSON or YAML file. The file is only
+// parsed again when its modification time or size changes, so it can be
+// rewritten in place while the pool is running.
+type FileProvider struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	nodes   []string
+}
+
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+func (p *FileProvider) Nodes(_ context.Context) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stat, err := os.Stat(p.path)
+	if err != nil {
+		return nil, err
+	}
+	if p.nodes != nil && stat.ModTime().Equal(p.modTime) && stat.Size() == p.size {
+		return append([]string(nil), p.nodes...), nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(p.path))
+	nodes, err := parseNodeList(data, ext == ".yaml" || ext == ".yml")
+	if err != nil {
+		return nil, err
+	}
+
+	p.nodes = nodes
+	p.modTime = stat.ModTime()
+	p.size = stat.Size()
+	return append([]string(nil), nodes...), nil
+}
+
+// HTTPProvider fetches the node list from a service with a GET request. The
+// response body is a JSON document in the same format as for FileProvider.
+type HTTPProvider struct {
+	url     string
+	httpCli *gohttp.Client
+	headers map[string]string
+}
+
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{
+		url:     url,
+		httpCli: gohttp.DefaultClient,
+		headers: make(map[string]string),
+	}
+}
+
+// SetHTTPClient replaces the client used to fetch the node list.
+func (p *HTTPProvider) SetHTTPClient(c *gohttp.Client) {
+	p.httpCli = c
+}
+
+// SetHeader adds a header to every node list request, e.g. for authorization.
+func (p *HTTPProvider) SetHeader(name, value string) {
+	p.headers[name] = value
+}
+
+func (p *HTTPProvider) Nodes(ctx context.Context) ([]string, error) {
+	req, err := gohttp.NewRequestWithContext(ctx, gohttp.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.httpCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != gohttp.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("fetch node list: unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseNodeList(data, false)
+}
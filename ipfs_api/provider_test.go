@@ -0,0 +1,204 @@
+package ipfs_api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+	"github.com/urchinfs/go-urchin2-sdk/utils"
+)
+
+func TestHTTPProvider(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"list", `["127.0.0.1:5001", "/ip4/127.0.0.1/tcp/5002"]`, []string{"127.0.0.1:5001", "/ip4/127.0.0.1/tcp/5002"}},
+		{"object", `{"nodes": ["127.0.0.1:5001"]}`, []string{"127.0.0.1:5001"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			p := NewHTTPProvider(ts.URL)
+			if _, err := p.Nodes(context.Background()); err == nil {
+				t.Error("unauthorized request succeeded")
+			}
+
+			p.SetHeader("Authorization", "Bearer secret")
+			got, err := p.Nodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("nodes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPProviderBadDocument(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>"))
+	}))
+	defer ts.Close()
+
+	if nodes, err := NewHTTPProvider(ts.URL).Nodes(context.Background()); err == nil {
+		t.Fatalf("nodes = %v, want a parse error", nodes)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		file string
+		data string
+	}{
+		{"nodes.json", `{"nodes": ["a:5001", "b:5001"]}`},
+		{"nodes.yaml", "nodes:\n  - a:5001\n  - b:5001\n"},
+		{"nodes.yml", "- a:5001\n- b:5001\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := NewFileProvider(path).Nodes(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"a:5001", "b:5001"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("nodes = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFileProviderReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.json")
+	if err := os.WriteFile(path, []byte(`["a:5001"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewFileProvider(path)
+	if got, err := p.Nodes(context.Background()); err != nil || len(got) != 1 {
+		t.Fatalf("nodes = %v, %v", got, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`["a:5001", "b:5001"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := p.Nodes(context.Background()); err != nil || len(got) != 2 {
+		t.Fatalf("nodes after rewrite = %v, %v", got, err)
+	}
+}
+
+// listProvider serves a node list the test can change.
+type listProvider struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (p *listProvider) set(addrs ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addrs = addrs
+}
+
+func (p *listProvider) Nodes(_ context.Context) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.addrs...), nil
+}
+
+func TestPoolRefresh(t *testing.T) {
+	a, _ := newTestClient(t)
+	b, _ := newTestClient(t)
+	provider := &listProvider{}
+	ctx := context.Background()
+
+	if _, err := NewClientPoolFromProvider(ctx, provider); err != utils.ErrNoNodes {
+		t.Fatalf("empty provider: %v, want ErrNoNodes", err)
+	}
+
+	provider.set(a.url)
+	p, err := NewClientPoolFromProvider(ctx, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	provider.set(a.url, b.url)
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(p.Nodes()); n != 2 {
+		t.Fatalf("%d nodes after refresh, want 2", n)
+	}
+
+	// An empty list keeps the current nodes.
+	provider.set()
+	if err := p.Refresh(ctx); err != utils.ErrNoNodes {
+		t.Errorf("refresh with no nodes = %v, want ErrNoNodes", err)
+	}
+	provider.set(b.url)
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := p.Nodes(); len(nodes) != 1 || nodes[0].Addr != b.url {
+		t.Errorf("nodes = %+v, want only %s", nodes, b.url)
+	}
+}
+
+func TestPoolRefreshInterval(t *testing.T) {
+	a, _ := newTestClient(t)
+	b, _ := newTestClient(t)
+	provider := &listProvider{addrs: []string{a.url}}
+	ctx := context.Background()
+
+	if _, err := NewClientPoolFromProvider(ctx, provider, options.Pool.RefreshInterval(0)); err == nil {
+		t.Fatal("zero refresh interval accepted")
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addrs, _ := provider.Nodes(r.Context())
+		json.NewEncoder(w).Encode(map[string][]string{"nodes": addrs})
+	}))
+	defer ts.Close()
+
+	p, err := NewClientPoolFromProvider(ctx, NewHTTPProvider(ts.URL), options.Pool.RefreshInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	provider.set(a.url, b.url)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.Nodes()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("nodes = %+v, the new node never joined", p.Nodes())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -4,17 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
-
-	"github.com/ipfs/boxo/files"
 )
 
+// multipartBody is implemented by files.MultiFileReader and by readers
+// wrapping one.
+type multipartBody interface {
+	Boundary() string
+}
+
 type Request struct {
 	Ctx     context.Context
 	ApiBase string
@@ -45,14 +48,15 @@ func NewRequest(ctx context.Context, url, command string, args ...string) *Reque
 }
 
 type trailerReader struct {
-	resp *http.Response
+	resp    *http.Response
+	command string
 }
 
 func (r *trailerReader) Read(b []byte) (int, error) {
 	n, err := r.resp.Body.Read(b)
 	if err != nil {
 		if e := r.resp.Trailer.Get("X-Stream-Error"); e != "" {
-			err = errors.New(e)
+			err = &StreamError{Command: r.command, Message: e}
 		}
 	}
 	return n, err
@@ -62,6 +66,20 @@ func (r *trailerReader) Close() error {
 	return r.resp.Body.Close()
 }
 
+// ctxReader stops a streaming read as soon as its context is done, so
+// long-running extraction and copy loops notice cancellation between reads.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(b)
+}
+
 type Response struct {
 	Output io.ReadCloser
 	Error  *Error
@@ -94,6 +112,9 @@ type Error struct {
 	Command string
 	Message string
 	Code    int
+
+	// StatusCode is the HTTP status of the failed response.
+	StatusCode int `json:"-"`
 }
 
 func (e *Error) Error() string {
@@ -120,14 +141,14 @@ func (r *Request) Send(c *http.Client) (*Response, error) {
 		req.Header.Add(k, v)
 	}
 
-	if fr, ok := r.Body.(*files.MultiFileReader); ok {
+	if fr, ok := r.Body.(multipartBody); ok && fr.Boundary() != "" {
 		req.Header.Set("Content-Type", "multipart/form-data; boundary="+fr.Boundary())
 		req.Header.Set("Content-Disposition", "form-data; name=\"files\"")
 	}
 
 	resp, err := c.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, &TransportError{Command: r.Command, Err: err}
 	}
 
 	contentType := resp.Header.Get("Content-Type")
@@ -136,10 +157,11 @@ func (r *Request) Send(c *http.Client) (*Response, error) {
 
 	nresp := new(Response)
 
-	nresp.Output = &trailerReader{resp}
+	nresp.Output = &trailerReader{resp: resp, command: r.Command}
 	if resp.StatusCode >= http.StatusBadRequest {
 		e := &Error{
-			Command: r.Command,
+			Command:    r.Command,
+			StatusCode: resp.StatusCode,
 		}
 		switch {
 		case resp.StatusCode == http.StatusNotFound:
@@ -190,6 +212,9 @@ type RequestBuilder struct {
 	opts    map[string]string
 	headers map[string]string
 	body    io.Reader
+	bodyFn  func() (io.Reader, error)
+
+	idempotent *bool
 
 	client *HttpClient
 }
@@ -207,11 +232,46 @@ func (r *RequestBuilder) BodyBytes(body []byte) *RequestBuilder {
 	return r.Body(bytes.NewReader(body))
 }
 
+// Body sets the request body. Bodies that implement io.Seeker are rewound to
+// their current offset when the request is retried.
 func (r *RequestBuilder) Body(body io.Reader) *RequestBuilder {
 	r.body = body
+	r.bodyFn = nil
+	if seeker, ok := body.(io.Seeker); ok {
+		if offset, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			r.bodyFn = func() (io.Reader, error) {
+				if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+					return nil, err
+				}
+				return body, nil
+			}
+		}
+	}
+	return r
+}
+
+// BodyFunc sets a function building the request body. It is called once per
+// attempt, which makes requests with streamed bodies such as multipart
+// uploads safe to retry.
+func (r *RequestBuilder) BodyFunc(fn func() (io.Reader, error)) *RequestBuilder {
+	r.body = nil
+	r.bodyFn = fn
 	return r
 }
 
+// Idempotent overrides whether the command may be retried automatically.
+func (r *RequestBuilder) Idempotent(idempotent bool) *RequestBuilder {
+	r.idempotent = &idempotent
+	return r
+}
+
+func (r *RequestBuilder) isIdempotent() bool {
+	if r.idempotent != nil {
+		return *r.idempotent
+	}
+	return idempotentCommands[r.command]
+}
+
 func (r *RequestBuilder) Option(key string, value interface{}) *RequestBuilder {
 	var s string
 	switch v := value.(type) {
@@ -240,15 +300,73 @@ func (r *RequestBuilder) Header(name, value string) *RequestBuilder {
 	return r
 }
 
+// Send performs the request, retrying idempotent commands under the client's
+// RetryPolicy. A command with a body is only retried when the body can be
+// rebuilt; otherwise the failure is reported wrapped in ErrBodyNotRewindable.
 func (r *RequestBuilder) Send(ctx context.Context) (*Response, error) {
+	policy := r.client.retry
+	attempts := policy.attempts()
+
+	for attempt := 1; ; attempt++ {
+		body := r.body
+		if r.bodyFn != nil {
+			var err error
+			if body, err = r.bodyFn(); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := r.send(ctx, body)
+		if attempt >= attempts || !r.isIdempotent() || !policy.retryable(resp, err) {
+			return resp, err
+		}
+
+		if err == nil {
+			err = resp.Error
+		}
+		if r.body != nil && r.bodyFn == nil {
+			return nil, fmt.Errorf("%w: %w", ErrBodyNotRewindable, err)
+		}
+
+		delay := policy.backoff(attempt)
+		log.Warnf("ipfs-api: %s attempt %d/%d failed, retrying in %s: %v", r.command, attempt, attempts, delay, err)
+		if err := wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *RequestBuilder) send(ctx context.Context, body io.Reader) (*Response, error) {
+	auth, err := r.client.authorization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string, len(r.client.headers)+len(r.headers)+1)
+	for k, v := range r.client.headers {
+		headers[k] = v
+	}
+	if auth != "" {
+		headers["Authorization"] = auth
+	}
+	for k, v := range r.headers {
+		headers[k] = v
+	}
+
 	req := NewRequest(ctx, r.client.url, r.command, r.args...)
 	req.Opts = r.opts
-	req.Headers = r.headers
-	req.Body = r.body
+	req.Headers = headers
+	req.Body = body
 	return req.Send(&r.client.httpCli)
 }
 
 func (r *RequestBuilder) Exec(ctx context.Context, res interface{}) error {
+	if timeout := r.client.requestTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	httpRes, err := r.Send(ctx)
 	if err != nil {
 		return err
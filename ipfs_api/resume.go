@@ -0,0 +1,376 @@
+package ipfs_api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	sdkcid "github.com/urchinfs/go-urchin2-sdk/cid"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+	"golang.org/x/sync/errgroup"
+)
+
+// resumeStateFile records the files of a resumable Get already written to
+// the output directory. It is removed once the download completes.
+const resumeStateFile = ".urchin-get.state"
+
+// resumeRecord is a line of the state file: a file fully downloaded from
+// Cid, as it was on disk right after the download.
+type resumeRecord struct {
+	Path    string `json:"path"`
+	Cid     string `json:"cid"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+}
+
+// resumeState is an append-only log of resumeRecords, headed by the root it
+// belongs to, so each finished file costs a single small write.
+type resumeState struct {
+	mu   sync.Mutex
+	f    *os.File
+	enc  *json.Encoder
+	done map[string]resumeRecord
+}
+
+func openResumeState(dir, root string) (*resumeState, error) {
+	name := filepath.Join(dir, resumeStateFile)
+	s := &resumeState{done: make(map[string]resumeRecord)}
+
+	if f, err := os.Open(name); err == nil {
+		dec := json.NewDecoder(bufio.NewReader(f))
+		var header struct {
+			Root string `json:"root"`
+		}
+		if dec.Decode(&header) == nil && header.Root == root {
+			for {
+				var rec resumeRecord
+				// A torn last line is simply downloaded again.
+				if dec.Decode(&rec) != nil {
+					break
+				}
+				s.done[rec.Path] = rec
+			}
+		}
+		f.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// Rewrite the log so it only holds the records still valid.
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	s.f = f
+	s.enc = json.NewEncoder(f)
+	if err := s.enc.Encode(map[string]string{"root": root}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	for _, rec := range s.done {
+		if err := s.enc.Encode(rec); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// upToDate tells whether dest still holds the file recorded for rel.
+func (s *resumeState) upToDate(rel, dest string, c cid.Cid) bool {
+	s.mu.Lock()
+	rec, ok := s.done[rel]
+	s.mu.Unlock()
+	if !ok || rec.Cid != c.String() {
+		return false
+	}
+
+	fi, err := os.Stat(dest)
+	return err == nil && fi.Size() == rec.Size && fi.ModTime().UnixNano() == rec.ModTime
+}
+
+func (s *resumeState) record(rel, dest string, c cid.Cid) error {
+	fi, err := os.Stat(dest)
+	if err != nil {
+		return err
+	}
+	rec := resumeRecord{Path: rel, Cid: c.String(), Size: fi.Size(), ModTime: fi.ModTime().UnixNano()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done[rel] = rec
+	return s.enc.Encode(rec)
+}
+
+func (s *resumeState) close() error {
+	return s.f.Close()
+}
+
+// resumeProgress serialises the progress reports of concurrent downloads.
+type resumeProgress struct {
+	mu sync.Mutex
+	p  *downloadProgress
+}
+
+func (r *resumeProgress) received(n int) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.p.received(n)
+}
+
+func (r *resumeProgress) file(path string) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.p.ev.Path = path
+	r.p.ev.Files++
+	return r.p.emit()
+}
+
+func (r *resumeProgress) Write(b []byte) (int, error) {
+	return len(b), r.received(len(b))
+}
+
+// getResumable downloads hash into outDir file by file with ranged cat
+// requests. Files are written to temporary files renamed once complete, and
+// an interrupted temporary file is continued rather than started again.
+func (h *HttpClient) getResumable(ctx context.Context, hash, outDir string, settings *options.DownloadSettings) error {
+	stat, err := h.FilesStat(ctx, ipfsPath(hash))
+	if err != nil {
+		return err
+	}
+
+	var progress *resumeProgress
+	if settings.Events != nil {
+		total := int64(stat.Size)
+		if stat.Type == sdkcid.TDirectory {
+			total = int64(stat.CumulativeSize)
+		}
+		progress = &resumeProgress{p: newDownloadProgress(ctx, settings.Events, hash, total)}
+	}
+
+	if stat.Type != sdkcid.TDirectory {
+		if err := h.fetchFile(ctx, stat.Cid, int64(stat.Size), outDir, progress, settings); err != nil {
+			return err
+		}
+		if progress != nil {
+			return progress.p.done()
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	state, err := openResumeState(outDir, stat.Cid.String())
+	if err != nil {
+		return err
+	}
+	defer state.close()
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(settings.Concurrency + 1)
+	g.Go(func() error {
+		entries, err := h.Walk(ctx, hash)
+		if err != nil {
+			return err
+		}
+
+		root := strings.TrimSuffix(hash, "/") + "/"
+		for entry := range entries {
+			if entry.Err != nil {
+				return entry.Err
+			}
+
+			rel := strings.TrimPrefix(entry.Path, root)
+			if !filepath.IsLocal(rel) {
+				return fmt.Errorf("refusing to write %q outside of %s", entry.Path, outDir)
+			}
+			dest := filepath.Join(outDir, filepath.FromSlash(rel))
+
+			switch entry.Type {
+			case sdkcid.TDirectory:
+				if err := os.MkdirAll(dest, 0755); err != nil {
+					return err
+				}
+			case sdkcid.TSymlink:
+				if _, err := os.Lstat(dest); os.IsNotExist(err) {
+					if err := os.Symlink(entry.Target, dest); err != nil {
+						return err
+					}
+				}
+			default:
+				entry := entry
+				g.Go(func() error {
+					if !state.upToDate(rel, dest, entry.Cid) && !sameLocalFile(ctx, dest, entry.Cid, int64(entry.Size)) {
+						if err := h.fetchFile(ctx, entry.Cid, int64(entry.Size), dest, progress, settings); err != nil {
+							return fmt.Errorf("%s: %w", entry.Path, err)
+						}
+					} else if err := progress.received(int(entry.Size)); err != nil {
+						return err
+					}
+					if err := progress.file(entry.Path); err != nil {
+						return err
+					}
+					return state.record(rel, dest, entry.Cid)
+				})
+			}
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	state.close()
+	if err := os.Remove(filepath.Join(outDir, resumeStateFile)); err != nil {
+		return err
+	}
+	if progress != nil {
+		return progress.p.done()
+	}
+	return nil
+}
+
+// sameLocalFile tells whether the file at dest, written by some earlier
+// download, already holds c. The CID is recomputed with the default
+// parameters of the CID version of c, so content added with other settings
+// is downloaded again.
+func sameLocalFile(ctx context.Context, dest string, c cid.Cid, size int64) bool {
+	fi, err := os.Stat(dest)
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() != size {
+		return false
+	}
+
+	local, err := localCid(ctx, dest, c)
+	return err == nil && local.Equals(c)
+}
+
+// partName is the temporary file dest is downloaded into. It is tied to the
+// content, so a leftover of another version of the file is never continued.
+func partName(dest string, c cid.Cid) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(filepath.Base(dest)))
+	return filepath.Join(filepath.Dir(dest), fmt.Sprintf(".%s-%08x.part", c, hash.Sum32()))
+}
+
+// fetchFile downloads the file c of the given size to dest, continuing a
+// previous partial download when there is one. With Verify a continued file
+// is hashed before it is moved into place, and downloaded again from the
+// start when it does not match c.
+func (h *HttpClient) fetchFile(ctx context.Context, c cid.Cid, size int64, dest string, progress *resumeProgress, settings *options.DownloadSettings) error {
+	tmp := partName(dest, c)
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	off, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if off > size {
+		if off, err = restartPart(f); err != nil {
+			return err
+		}
+	}
+	if err := progress.received(int(off)); err != nil {
+		return err
+	}
+
+	var w io.Writer = f
+	if progress != nil {
+		w = io.MultiWriter(f, progress)
+	}
+
+	resumed := off > 0
+	if err := h.catInto(ctx, w, c, off, size); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if resumed && settings.Verify {
+		local, err := localCid(ctx, tmp, c, settings.VerifyAdd...)
+		if err != nil {
+			return err
+		}
+		if !local.Equals(c) {
+			log.Warnf("continued download of %s hashes to %s, starting over", c, local)
+			if _, err := restartPart(f); err != nil {
+				return err
+			}
+			if err := h.catInto(ctx, w, c, 0, size); err != nil {
+				return err
+			}
+			if err := f.Sync(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// restartPart empties a partial download.
+func restartPart(f *os.File) (int64, error) {
+	if err := f.Truncate(0); err != nil {
+		return 0, err
+	}
+	return f.Seek(0, io.SeekStart)
+}
+
+// catInto writes the bytes of the file c from off to size to w, resuming
+// the cat request when the stream breaks.
+func (h *HttpClient) catInto(ctx context.Context, w io.Writer, c cid.Cid, off, size int64) error {
+	failures := 0
+	for off < size {
+		rc, err := h.CatRange(ctx, c.String(), off, -1)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(w, rc)
+		rc.Close()
+		off += n
+
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if isPermanent(err) {
+			return err
+		}
+		if n > 0 {
+			failures = 0
+		} else {
+			failures++
+		}
+		if failures >= maxResumes {
+			return err
+		}
+		log.Warnf("cat %s broke at offset %d, resuming: %v", c, off, err)
+	}
+	if off != size {
+		return fmt.Errorf("got %d bytes of %s, expected %d", off, c, size)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package ipfs_api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	gohttp "net/http"
+	"syscall"
+	"time"
+
+	"github.com/urchinfs/go-urchin2-sdk/utils"
+)
+
+// ErrBodyNotRewindable is returned, wrapped together with the original error,
+// when a failed request could be retried but its body can't be read again.
+var ErrBodyNotRewindable = errors.New("request body can not be rewound for retry")
+
+// idempotentCommands are retried automatically under the client's
+// RetryPolicy. Uploads are content addressed, so sending the same data again
+// is harmless; they are only retried when the body can be rebuilt, see
+// RequestBuilder.BodyFunc.
+var idempotentCommands = map[string]bool{
+	"cat":          true,
+	"ls":           true,
+	"get":          true,
+	"dag/export":   true,
+	"dag/get":      true,
+	"version":      true,
+	"pin/ls":       true,
+	"pin/verify":   true,
+	"files/read":   true,
+	"files/ls":     true,
+	"files/stat":   true,
+	"name/resolve": true,
+	"routing/get":  true,
+	"key/list":     true,
+	"block/get":    true,
+	"block/stat":   true,
+	"dag/stat":     true,
+	"dag/resolve":  true,
+
+	"add":        true,
+	"dag/import": true,
+	"block/put":  true,
+	"dag/put":    true,
+	"pin/add":    true,
+}
+
+// RetryPolicy controls how failed requests are retried. Attempts are spaced
+// with exponential backoff, each delay being randomly shortened by up to
+// Jitter of its length.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64
+
+	// RetryableStatus lists the HTTP status codes worth another attempt.
+	RetryableStatus []int
+	// RetryableError, when set, is consulted for transport errors that the
+	// built-in classification does not consider retryable.
+	RetryableError func(error) bool
+}
+
+// DefaultRetryPolicy retries connection failures and gateway errors from
+// reverse proxies up to three times.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatus: []int{
+			gohttp.StatusTooManyRequests,
+			gohttp.StatusBadGateway,
+			gohttp.StatusServiceUnavailable,
+			gohttp.StatusGatewayTimeout,
+		},
+	}
+}
+
+func (p *RetryPolicy) attempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the delay before the given retry, starting at 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff)
+	if p.Multiplier > 1 {
+		d *= math.Pow(p.Multiplier, float64(retry-1))
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d -= d * p.Jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// retryable reports whether the outcome of an attempt is worth retrying.
+func (p *RetryPolicy) retryable(resp *Response, err error) bool {
+	if err == nil {
+		if resp == nil || resp.Error == nil || isPermanent(resp.Error) {
+			return false
+		}
+		for _, code := range p.RetryableStatus {
+			if resp.Error.StatusCode == code {
+				return true
+			}
+		}
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if isTLSErr(err) {
+		return false
+	}
+	if isTransientErr(err) || errors.Is(err, ErrTimeout) || errors.Is(err, utils.ErrOffline) {
+		return true
+	}
+	return p.RetryableError != nil && p.RetryableError(err)
+}
+
+// isTransientErr recognises failures of the connection itself. Every error of
+// http.Client.Do is a net.Error, so the wrapped cause is what gets checked.
+func isTransientErr(err error) bool {
+	switch {
+	case errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isTLSErr recognises certificate and handshake failures, which another
+// attempt would only repeat.
+func isTLSErr(err error) bool {
+	var (
+		verifyErr   *tls.CertificateVerificationError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+		authErr     x509.UnknownAuthorityError
+		hostErr     x509.HostnameError
+		invalidErr  x509.CertificateInvalidError
+		rootsErr    x509.SystemRootsError
+		constrained x509.ConstraintViolationError
+	)
+	return errors.As(err, &verifyErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &authErr) ||
+		errors.As(err, &hostErr) ||
+		errors.As(err, &invalidErr) ||
+		errors.As(err, &rootsErr) ||
+		errors.As(err, &constrained)
+}
+
+// wait sleeps for d or until ctx is done.
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,175 @@
+package ipfs_api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/testserver"
+	"github.com/urchinfs/go-urchin2-sdk/utils"
+)
+
+func TestRetryStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		fault    testserver.Fault
+		requests int
+		want     error
+	}{
+		{
+			name:     "rate limited",
+			fault:    testserver.Fault{Status: http.StatusTooManyRequests, Times: 2},
+			requests: 3,
+		},
+		{
+			name:     "bad gateway",
+			fault:    testserver.Fault{Status: http.StatusBadGateway, Times: 1},
+			requests: 2,
+		},
+		{
+			name:     "rate limited too long",
+			fault:    testserver.Fault{Status: http.StatusTooManyRequests},
+			requests: 3,
+			want:     &Error{},
+		},
+		{
+			name:     "not found",
+			fault:    testserver.Fault{Status: http.StatusServiceUnavailable, Message: "block was not found locally (offline)"},
+			requests: 1,
+			want:     ErrNotFound,
+		},
+		{
+			name:     "internal error",
+			fault:    testserver.Fault{Status: http.StatusInternalServerError},
+			requests: 1,
+			want:     &Error{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, s := newTestClient(t)
+			s.Inject("version", tt.fault)
+
+			_, _, err := c.Version(context.Background())
+			switch want := tt.want.(type) {
+			case nil:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case *Error:
+				var rpcErr *Error
+				if !errors.As(err, &rpcErr) || rpcErr.StatusCode != tt.fault.Status {
+					t.Fatalf("err = %v, want an RPC error with status %d", err, tt.fault.Status)
+				}
+				if errors.Is(err, ErrNotFound) {
+					t.Errorf("%v classified as not found", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("err = %v, want %v", err, want)
+				}
+			}
+			if n := s.Requests("version"); n != tt.requests {
+				t.Errorf("%d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRetryConnectionRefused(t *testing.T) {
+	s := testserver.New()
+	s.Close()
+	c, err := NewClient(s.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attempts atomic.Int32
+	policy := fastRetry()
+	policy.RetryableError = func(error) bool {
+		attempts.Add(1)
+		return false
+	}
+	c.SetRetryPolicy(policy)
+
+	_, _, err = c.Version(context.Background())
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) || !errors.Is(err, utils.ErrOffline) {
+		t.Fatalf("err = %v, want an offline TransportError", err)
+	}
+	if !policy.retryable(nil, err) {
+		t.Error("refused connection not retryable")
+	}
+	if attempts.Load() != 0 {
+		t.Error("refused connection left to RetryableError")
+	}
+}
+
+func TestNoRetryTLS(t *testing.T) {
+	var conns atomic.Int32
+	ts := httptest.NewUnstartedServer(testserver.NewUnstarted())
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	// The certificate of the test server is not trusted by default.
+	c, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRetryPolicy(fastRetry())
+	_, _, err = c.Version(context.Background())
+	if !isTLSErr(err) {
+		t.Fatalf("err = %v, want a certificate error", err)
+	}
+	if c.retry.retryable(nil, err) {
+		t.Error("certificate error retryable")
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("%d connections, want 1", n)
+	}
+
+	c, err = NewClient(ts.URL, options.Client.RootCAs(ts.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Version(context.Background()); err != nil {
+		t.Fatalf("trusted server: %v", err)
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    *Error
+		target error
+		want   bool
+	}{
+		{"unknown command", &Error{StatusCode: 404, Message: "command not found"}, ErrCommandNotFound, true},
+		{"unknown command is not content", &Error{StatusCode: 404, Message: "command not found"}, ErrNotFound, false},
+		{"missing block", &Error{StatusCode: 500, Message: "block was not found locally (offline)"}, ErrNotFound, true},
+		{"missing block is not offline", &Error{StatusCode: 500, Message: "block was not found locally (offline)"}, utils.ErrOffline, false},
+		{"missing link", &Error{StatusCode: 500, Message: "no link named \"x\" under Qm..."}, ErrNotFound, true},
+		{"rate limited", &Error{StatusCode: 500, Code: codeRateLimited, Message: "provider records not found in time"}, ErrNotFound, false},
+		{"forbidden code", &Error{StatusCode: 500, Code: codeForbidden}, ErrUnauthorized, true},
+		{"forbidden status", &Error{StatusCode: 403}, ErrUnauthorized, true},
+		{"gateway timeout", &Error{StatusCode: 504}, ErrTimeout, true},
+		{"deadline", &Error{StatusCode: 500, Message: "context deadline exceeded"}, ErrTimeout, true},
+		{"offline node", &Error{StatusCode: 500, Message: "this action must be run in online mode"}, utils.ErrOffline, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.err, tt.target, got, tt.want)
+			}
+		})
+	}
+}
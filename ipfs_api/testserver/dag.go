@@ -0,0 +1,419 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	carv1 "github.com/ipld/go-car"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	_ "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	_ "github.com/ipld/go-ipld-prime/codec/dagjson"
+	_ "github.com/ipld/go-ipld-prime/codec/raw"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/multicodec"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	selectorparse "github.com/ipld/go-ipld-prime/traversal/selector/parse"
+	mc "github.com/multiformats/go-multicodec"
+	mh "github.com/multiformats/go-multihash"
+)
+
+var errHashMismatch = errors.New("data in datastore does not match its cid")
+
+// walk visits every node of the DAG under root once, breadth first. A node
+// that cannot be read or does not match its CID is passed with an error and
+// its children are skipped; walk stops when visit returns an error.
+func (s *Server) walk(ctx context.Context, root cid.Cid, visit func(c cid.Cid, nd format.Node, err error) error) error {
+	seen := map[cid.Cid]bool{root: true}
+	queue := []cid.Cid{root}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+
+		nd, err := s.getNode(ctx, c)
+		if err == nil {
+			sum, serr := c.Prefix().Sum(nd.RawData())
+			if serr != nil {
+				err = serr
+			} else if !bytes.Equal(sum.Hash(), c.Hash()) {
+				err = errHashMismatch
+			}
+		}
+		if verr := visit(c, nd, err); verr != nil {
+			return verr
+		}
+		if err != nil {
+			continue
+		}
+		for _, l := range nd.Links() {
+			if !seen[l.Cid] {
+				seen[l.Cid] = true
+				queue = append(queue, l.Cid)
+			}
+		}
+	}
+	return nil
+}
+
+// complete checks that every block of the DAG under root is present and
+// sound, and returns the number of nodes.
+func (s *Server) complete(ctx context.Context, root cid.Cid) (int, error) {
+	n := 0
+	err := s.walk(ctx, root, func(c cid.Cid, _ format.Node, err error) error {
+		if err != nil {
+			return fmt.Errorf("%s: %w", c, err)
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+func (s *Server) dagImport(w *responseWriter, r *request) error {
+	mediatype, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	mpr := multipart.NewReader(r.Body, params["boundary"])
+	if mediatype != "multipart/form-data" {
+		return fmt.Errorf("dag/import: expected a multipart body, got %s", mediatype)
+	}
+
+	ctx := r.Context()
+	var roots []cid.Cid
+	var count, size uint64
+	for {
+		part, err := mpr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		cr, err := carv1.NewCarReader(part)
+		if err != nil {
+			return err
+		}
+		roots = append(roots, cr.Header.Roots...)
+		for {
+			blk, err := cr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if err := s.bs.Put(ctx, blk); err != nil {
+				return err
+			}
+			count++
+			size += uint64(len(blk.RawData()))
+		}
+	}
+
+	if r.boolOpt("pin-roots", true) {
+		for _, c := range roots {
+			msg := ""
+			if _, err := s.complete(ctx, c); err != nil {
+				msg = err.Error()
+			} else {
+				s.mu.Lock()
+				s.pins[c] = pinEntry{typ: "recursive"}
+				s.mu.Unlock()
+			}
+			root := map[string]interface{}{
+				"Cid":         map[string]string{"/": c.String()},
+				"PinErrorMsg": msg,
+			}
+			if err := emit(w, map[string]interface{}{"Root": root}); err != nil {
+				return err
+			}
+		}
+	}
+	if r.boolOpt("stats", false) {
+		stats := map[string]uint64{"BlockCount": count, "BlockBytesCount": size}
+		return emit(w, map[string]interface{}{"Stats": stats})
+	}
+	return nil
+}
+
+func (s *Server) dagExport(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("dag/export: expected a single root")
+	}
+	c, _, err := splitPath(r.args[0])
+	if err != nil {
+		return err
+	}
+	ctx := r.Context()
+	if _, err := s.getNode(ctx, c); err != nil {
+		return err
+	}
+
+	car := carv1.NewSelectiveCar(ctx, s.bs, []carv1.Dag{{
+		Root:     c,
+		Selector: selectorparse.CommonSelector_ExploreAllRecursively,
+	}}, carv1.TraverseLinksOnlyOnce())
+	return car.Write(w)
+}
+
+func (s *Server) linkSystem(ctx context.Context) ipld.LinkSystem {
+	lsys := cidlink.DefaultLinkSystem()
+	lsys.StorageReadOpener = func(_ ipld.LinkContext, l ipld.Link) (io.Reader, error) {
+		blk, err := s.bs.Get(ctx, l.(cidlink.Link).Cid)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(blk.RawData()), nil
+	}
+	return lsys
+}
+
+// prototype picks the dag-pb schema for dag-pb blocks, as the decoder needs
+// it, and the basic node for everything else.
+func prototype(l ipld.Link) ipld.NodePrototype {
+	if l.(cidlink.Link).Cid.Type() == cid.DagProtobuf {
+		return dagpb.Type.PBNode
+	}
+	return basicnode.Prototype.Any
+}
+
+func (s *Server) dagGet(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("dag/get: expected a single path")
+	}
+	c, names, err := splitPath(r.args[0])
+	if err != nil {
+		return err
+	}
+
+	var codec mc.Code
+	if err := codec.Set(r.stringOpt("output-codec", "dag-json")); err != nil {
+		return err
+	}
+	encode, err := multicodec.LookupEncoder(uint64(codec))
+	if err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	lsys := s.linkSystem(ctx)
+	load := func(c cid.Cid) (datamodel.Node, error) {
+		l := cidlink.Link{Cid: c}
+		return lsys.Load(ipld.LinkContext{Ctx: ctx}, l, prototype(l))
+	}
+
+	nd, err := load(c)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if nd, err = nd.LookupBySegment(datamodel.ParsePathSegment(name)); err != nil {
+			return err
+		}
+		if nd.Kind() == datamodel.Kind_Link {
+			l, err := nd.AsLink()
+			if err != nil {
+				return err
+			}
+			if nd, err = load(l.(cidlink.Link).Cid); err != nil {
+				return err
+			}
+		}
+	}
+	return encode(nd, w)
+}
+
+// dagStatSummary is the output of dag/stat, with the same fields left
+// out as in Kubo.
+type dagStatSummary struct {
+	UniqueBlocks int        `json:",omitempty"`
+	TotalSize    uint64     `json:",omitempty"`
+	SharedSize   uint64     `json:",omitempty"`
+	Ratio        float32    `json:",omitempty"`
+	DagStats     []*dagStat `json:"DagStats,omitempty"`
+}
+
+type dagStat struct {
+	Cid       string
+	Size      uint64 `json:",omitempty"`
+	NumBlocks int64  `json:",omitempty"`
+}
+
+// dagStat walks the DAGs like Kubo: with progress it emits the summary after
+// every block, the totals only come with the last one. TotalSize counts the
+// blocks shared between DAGs once.
+func (s *Server) dagStat(w *responseWriter, r *request) error {
+	if len(r.args) == 0 {
+		return errors.New("dag/stat: argument \"root\" is required")
+	}
+
+	ctx := r.Context()
+	progress := r.boolOpt("progress", false)
+	unique := make(map[cid.Cid]bool)
+	var redundant uint64
+	summary := &dagStatSummary{}
+	for _, a := range r.args {
+		root, _, err := splitPath(a)
+		if err != nil {
+			return err
+		}
+		stat := &dagStat{Cid: root.String()}
+		summary.DagStats = append(summary.DagStats, stat)
+		err = s.walk(ctx, root, func(c cid.Cid, nd format.Node, err error) error {
+			if err != nil {
+				return err
+			}
+			n := uint64(len(nd.RawData()))
+			stat.Size += n
+			stat.NumBlocks++
+			if !unique[c] {
+				unique[c] = true
+				summary.TotalSize += n
+			}
+			redundant += n
+			if progress {
+				return emit(w, summary)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	summary.UniqueBlocks = len(unique)
+	if summary.TotalSize > 0 {
+		summary.Ratio = float32(redundant) / float32(summary.TotalSize)
+	}
+	summary.SharedSize = redundant - summary.TotalSize
+	return emit(w, summary)
+}
+
+func (s *Server) dagPut(w *responseWriter, r *request) error {
+	mediatype, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	if mediatype != "multipart/form-data" {
+		return fmt.Errorf("dag/put: expected a multipart body, got %s", mediatype)
+	}
+
+	var inCodec, storeCodec mc.Code
+	if err := inCodec.Set(r.stringOpt("input-codec", "dag-json")); err != nil {
+		return err
+	}
+	if err := storeCodec.Set(r.stringOpt("store-codec", "dag-cbor")); err != nil {
+		return err
+	}
+	decode, err := multicodec.LookupDecoder(uint64(inCodec))
+	if err != nil {
+		return err
+	}
+	encode, err := multicodec.LookupEncoder(uint64(storeCodec))
+	if err != nil {
+		return err
+	}
+	hash := r.stringOpt("hash", "sha2-256")
+	code, ok := mh.Names[hash]
+	if !ok {
+		return fmt.Errorf("unrecognized hash function: %q", hash)
+	}
+	prefix := cid.Prefix{Version: 1, Codec: uint64(storeCodec), MhType: code, MhLength: -1}
+
+	ctx := r.Context()
+	mpr := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := mpr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		nb := basicnode.Prototype.Any.NewBuilder()
+		if err := decode(nb, part); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := encode(nb.Build(), &buf); err != nil {
+			return err
+		}
+		c, err := prefix.Sum(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		blk, err := blocks.NewBlockWithCid(buf.Bytes(), c)
+		if err != nil {
+			return err
+		}
+		if err := s.bs.Put(ctx, blk); err != nil {
+			return err
+		}
+		if r.boolOpt("pin", false) {
+			s.mu.Lock()
+			s.pins[c] = pinEntry{typ: "recursive"}
+			s.mu.Unlock()
+		}
+		if err := emit(w, map[string]interface{}{"Cid": map[string]string{"/": c.String()}}); err != nil {
+			return err
+		}
+	}
+}
+
+// dagResolve follows the path through the IPLD data model, like dag/get,
+// and reports the last block reached and the path left inside it.
+func (s *Server) dagResolve(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("dag/resolve: expected a single path")
+	}
+	c, names, err := splitPath(r.args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	lsys := s.linkSystem(ctx)
+	load := func(c cid.Cid) (datamodel.Node, error) {
+		l := cidlink.Link{Cid: c}
+		return lsys.Load(ipld.LinkContext{Ctx: ctx}, l, prototype(l))
+	}
+
+	nd, err := load(c)
+	if err != nil {
+		return err
+	}
+	var rem []string
+	for _, name := range names {
+		if nd, err = nd.LookupBySegment(datamodel.ParsePathSegment(name)); err != nil {
+			return err
+		}
+		rem = append(rem, name)
+		if nd.Kind() == datamodel.Kind_Link {
+			l, err := nd.AsLink()
+			if err != nil {
+				return err
+			}
+			c = l.(cidlink.Link).Cid
+			if nd, err = load(c); err != nil {
+				return err
+			}
+			rem = nil
+		}
+	}
+	return emit(w, map[string]interface{}{
+		"Cid":     map[string]string{"/": c.String()},
+		"RemPath": strings.Join(rem, "/"),
+	})
+}
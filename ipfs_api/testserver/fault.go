@@ -0,0 +1,128 @@
+package testserver
+
+import (
+	"net/http"
+	"time"
+)
+
+// Fault describes how to disturb the answer to a command.
+type Fault struct {
+	// Latency delays the answer.
+	Latency time.Duration
+	// Status, when set, answers with this HTTP status and Message as the
+	// RPC error instead of running the command.
+	Status  int
+	Message string
+	// Truncate breaks the connection once TruncateAfter bytes of the
+	// output have been sent.
+	Truncate      bool
+	TruncateAfter int64
+	// StreamError is reported in the X-Stream-Error trailer after the
+	// output, as Kubo does when a command fails halfway.
+	StreamError string
+	// Times is the number of requests the fault applies to; zero means
+	// every request.
+	Times int
+}
+
+type faultRule struct {
+	Fault
+	command string
+	used    int
+}
+
+// Inject disturbs the next requests for command, or for every command when
+// command is empty. Rules apply in the order they were injected, one per
+// request.
+func (s *Server) Inject(command string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &faultRule{Fault: f, command: command})
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// takeFault picks the fault for a request to command. s.mu must be held.
+func (s *Server) takeFault(command string) *Fault {
+	for _, rule := range s.faults {
+		if rule.command != "" && rule.command != command {
+			continue
+		}
+		if rule.Times > 0 && rule.used >= rule.Times {
+			continue
+		}
+		rule.used++
+		f := rule.Fault
+		return &f
+	}
+	return nil
+}
+
+// apply runs the part of the fault happening before the command. It returns
+// false when the request has been answered.
+func (f *Fault) apply(w *responseWriter, r *http.Request) bool {
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			return false
+		}
+	}
+	if f.Status != 0 {
+		msg := f.Message
+		if msg == "" {
+			msg = http.StatusText(f.Status)
+		}
+		writeError(w, f.Status, msg)
+		return false
+	}
+	if f.Truncate {
+		w.limit = f.TruncateAfter
+		w.truncate = true
+	}
+	return true
+}
+
+// responseWriter counts the output of a command and cuts the connection when
+// a truncation fault is due.
+type responseWriter struct {
+	w        http.ResponseWriter
+	written  int64
+	truncate bool
+	limit    int64
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.w.Header()
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.w.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.truncate && w.written+int64(len(b)) > w.limit {
+		n, _ := w.w.Write(b[:w.limit-w.written])
+		w.written += int64(n)
+		w.Flush()
+		// Drop the connection without ending the chunked body, so the
+		// client sees a broken stream.
+		panic(http.ErrAbortHandler)
+	}
+	n, err := w.w.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
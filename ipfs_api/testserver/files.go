@@ -0,0 +1,558 @@
+package testserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	gopath "path"
+	"strings"
+
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	"github.com/ipfs/boxo/mfs"
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// filesRoot returns the MFS root of the node, an empty directory until the
+// first change.
+func (s *Server) filesRoot(ctx context.Context) (*mfs.Root, error) {
+	s.mfsOnce.Do(func() {
+		nd := ft.EmptyDirNode()
+		if s.mfsErr = s.dag.Add(ctx, nd); s.mfsErr != nil {
+			return
+		}
+		// Nothing is published under IPNS, but FlushPath waits on the
+		// republisher, so one is needed.
+		noPublish := func(context.Context, cid.Cid) error { return nil }
+		s.mfsRoot, s.mfsErr = mfs.NewRoot(context.Background(), s.dag, nd, noPublish)
+	})
+	return s.mfsRoot, s.mfsErr
+}
+
+// checkPath validates an MFS path the way Kubo does, keeping a trailing
+// slash.
+func checkPath(p string) (string, error) {
+	if p == "" {
+		return "", errors.New("paths must not be empty")
+	}
+	if p[0] != '/' {
+		return "", fmt.Errorf("paths must start with a leading slash")
+	}
+	cleaned := gopath.Clean(p)
+	if p[len(p)-1] == '/' && p != "/" {
+		cleaned += "/"
+	}
+	return cleaned, nil
+}
+
+// cidBuilder reads the cid-version and hash options. Without either, new
+// nodes keep the settings of their parent directory.
+func cidBuilder(r *request) (cid.Builder, error) {
+	_, versionSet := r.option("cid-version")
+	hash, hashSet := r.option("hash")
+	if !versionSet && !hashSet {
+		return nil, nil
+	}
+
+	version := r.intOpt("cid-version", 0)
+	if hashSet && version == 0 {
+		version = 1
+	}
+	prefix, err := merkledag.PrefixForCidVersion(int(version))
+	if err != nil {
+		return nil, err
+	}
+	if hashSet {
+		code, ok := mh.Names[strings.ToLower(hash)]
+		if !ok {
+			return nil, fmt.Errorf("unrecognized hash function: %q", hash)
+		}
+		prefix.MhType = code
+		prefix.MhLength = -1
+	}
+	return prefix, nil
+}
+
+// nodeAt returns the node at p, an /ipfs/ path or an MFS path.
+func (s *Server) nodeAt(ctx context.Context, p string) (format.Node, error) {
+	if strings.HasPrefix(p, "/ipfs/") || strings.HasPrefix(p, "/ipns/") {
+		return s.resolve(ctx, p)
+	}
+	root, err := s.filesRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fsn, err := mfs.Lookup(root, p)
+	if err != nil {
+		return nil, err
+	}
+	return fsn.GetNode()
+}
+
+// ensureParent creates the directory holding p, with its parents.
+func ensureParent(root *mfs.Root, p string, builder cid.Builder) error {
+	dir := gopath.Dir(p)
+	if dir == "/" {
+		return nil
+	}
+	return mfs.Mkdir(root, dir, mfs.MkdirOpts{Mkparents: true, CidBuilder: builder})
+}
+
+// fileHandle looks up the MFS file at p, creating an empty one when create
+// is set.
+func fileHandle(root *mfs.Root, p string, create bool, builder cid.Builder) (*mfs.File, error) {
+	target, err := mfs.Lookup(root, p)
+	switch {
+	case err == nil:
+		fi, ok := target.(*mfs.File)
+		if !ok {
+			return nil, fmt.Errorf("%s was not a file", p)
+		}
+		return fi, nil
+	case err != os.ErrNotExist || !create:
+		return nil, err
+	}
+
+	dirname, name := gopath.Split(p)
+	parent, err := mfs.Lookup(root, dirname)
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := parent.(*mfs.Directory)
+	if !ok {
+		return nil, fmt.Errorf("%s was not a directory", dirname)
+	}
+	if builder == nil {
+		builder = dir.GetCidBuilder()
+	}
+	nd := merkledag.NodeWithData(ft.FilePBData(nil, 0))
+	nd.SetCidBuilder(builder)
+	if err := dir.AddChild(name, nd); err != nil {
+		return nil, err
+	}
+	child, err := dir.Child(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, ok := child.(*mfs.File)
+	if !ok {
+		return nil, fmt.Errorf("%s was not a file", p)
+	}
+	return fi, nil
+}
+
+func (s *Server) filesMkdir(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("files/mkdir: expected a single path")
+	}
+	p, err := checkPath(r.args[0])
+	if err != nil {
+		return err
+	}
+	builder, err := cidBuilder(r)
+	if err != nil {
+		return err
+	}
+	root, err := s.filesRoot(r.Context())
+	if err != nil {
+		return err
+	}
+	return mfs.Mkdir(root, p, mfs.MkdirOpts{
+		Mkparents:  r.boolOpt("parents", false),
+		Flush:      r.boolOpt("flush", true),
+		CidBuilder: builder,
+	})
+}
+
+// fileArg opens the single file sent in the multipart body of r.
+func fileArg(r *request) (files.File, error) {
+	mediatype, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(mediatype, "multipart/") {
+		return nil, fmt.Errorf("expected a multipart body, got %s", mediatype)
+	}
+	dir, err := files.NewFileFromPartReader(multipart.NewReader(r.Body, params["boundary"]), mediatype)
+	if err != nil {
+		return nil, err
+	}
+	it := dir.Entries()
+	if !it.Next() {
+		if it.Err() != nil {
+			return nil, it.Err()
+		}
+		return nil, errors.New("file argument \"data\" is required")
+	}
+	f, ok := it.Node().(files.File)
+	if !ok {
+		return nil, errors.New("expected a file argument")
+	}
+	return f, nil
+}
+
+func (s *Server) filesWrite(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("files/write: expected a single path")
+	}
+	p, err := checkPath(r.args[0])
+	if err != nil {
+		return err
+	}
+	builder, err := cidBuilder(r)
+	if err != nil {
+		return err
+	}
+	offset := r.intOpt("offset", 0)
+	if offset < 0 {
+		return errors.New("cannot have negative write offset")
+	}
+	root, err := s.filesRoot(r.Context())
+	if err != nil {
+		return err
+	}
+
+	if r.boolOpt("parents", false) {
+		if err := ensureParent(root, p, builder); err != nil {
+			return err
+		}
+	}
+	fi, err := fileHandle(root, p, r.boolOpt("create", false), builder)
+	if err != nil {
+		return err
+	}
+	if _, ok := r.option("raw-leaves"); ok {
+		fi.RawLeaves = r.boolOpt("raw-leaves", false)
+	}
+
+	in, err := fileArg(r)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	wfd, err := fi.Open(mfs.Flags{Write: true, Sync: r.boolOpt("flush", true)})
+	if err != nil {
+		return err
+	}
+	if r.boolOpt("truncate", false) {
+		if err := wfd.Truncate(0); err != nil {
+			wfd.Close()
+			return err
+		}
+	}
+	if _, err := wfd.Seek(offset, io.SeekStart); err != nil {
+		wfd.Close()
+		return err
+	}
+	var rd io.Reader = in
+	if _, ok := r.option("count"); ok {
+		count := r.intOpt("count", 0)
+		if count < 0 {
+			wfd.Close()
+			return errors.New("cannot have negative byte count")
+		}
+		rd = io.LimitReader(rd, count)
+	}
+	if _, err := io.Copy(wfd, rd); err != nil {
+		wfd.Close()
+		return err
+	}
+	return wfd.Close()
+}
+
+func (s *Server) filesRead(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("files/read: expected a single path")
+	}
+	p, err := checkPath(r.args[0])
+	if err != nil {
+		return err
+	}
+	root, err := s.filesRoot(r.Context())
+	if err != nil {
+		return err
+	}
+	fsn, err := mfs.Lookup(root, p)
+	if err != nil {
+		return err
+	}
+	fi, ok := fsn.(*mfs.File)
+	if !ok {
+		return fmt.Errorf("%s was not a file", p)
+	}
+
+	rfd, err := fi.Open(mfs.Flags{Read: true})
+	if err != nil {
+		return err
+	}
+	defer rfd.Close()
+
+	offset := r.intOpt("offset", 0)
+	if offset < 0 {
+		return errors.New("cannot specify negative offset")
+	}
+	size, err := rfd.Size()
+	if err != nil {
+		return err
+	}
+	if offset > size {
+		return fmt.Errorf("offset was past end of file (%d > %d)", offset, size)
+	}
+	if _, err := rfd.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	var rd io.Reader = rfd
+	if _, ok := r.option("count"); ok {
+		count := r.intOpt("count", 0)
+		if count < 0 {
+			return errors.New("cannot specify negative 'count'")
+		}
+		rd = io.LimitReader(rd, count)
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	_, err = io.Copy(w, rd)
+	return err
+}
+
+func (s *Server) filesLs(w *responseWriter, r *request) error {
+	arg := "/"
+	if len(r.args) > 0 {
+		arg = r.args[0]
+	}
+	p, err := checkPath(arg)
+	if err != nil {
+		return err
+	}
+	ctx := r.Context()
+	root, err := s.filesRoot(ctx)
+	if err != nil {
+		return err
+	}
+	fsn, err := mfs.Lookup(root, p)
+	if err != nil {
+		return err
+	}
+
+	long := r.boolOpt("long", false)
+	var entries []mfs.NodeListing
+	switch fsn := fsn.(type) {
+	case *mfs.Directory:
+		if long {
+			if entries, err = fsn.List(ctx); err != nil {
+				return err
+			}
+			break
+		}
+		names, err := fsn.ListNames(ctx)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			entries = append(entries, mfs.NodeListing{Name: name})
+		}
+	case *mfs.File:
+		entry := mfs.NodeListing{Name: gopath.Base(p)}
+		if long {
+			entry.Type = int(fsn.Type())
+			if entry.Size, err = fsn.Size(); err != nil {
+				return err
+			}
+			nd, err := fsn.GetNode()
+			if err != nil {
+				return err
+			}
+			entry.Hash = nd.Cid().String()
+		}
+		entries = append(entries, entry)
+	}
+	return emit(w, map[string]interface{}{"Entries": entries})
+}
+
+// filesStat describes an /ipfs/ path or an MFS path.
+func (s *Server) filesStat(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("files/stat: expected a single path")
+	}
+	p, err := checkPath(r.args[0])
+	if err != nil {
+		return err
+	}
+
+	nd, err := s.nodeAt(r.Context(), p)
+	if err != nil {
+		return err
+	}
+	info, err := describe(nd)
+	if err != nil {
+		return err
+	}
+	cumulative, err := nd.Size()
+	if err != nil {
+		return err
+	}
+
+	typ := "file"
+	switch info.typ {
+	case ft.TDirectory, ft.THAMTShard:
+		typ = "directory"
+	case ft.TSymlink:
+		typ = "symlink"
+	}
+	return emit(w, map[string]interface{}{
+		"Hash":           nd.Cid().String(),
+		"Size":           info.size,
+		"CumulativeSize": cumulative,
+		"Blocks":         len(nd.Links()),
+		"Type":           typ,
+	})
+}
+
+func (s *Server) filesCp(w *responseWriter, r *request) error {
+	if len(r.args) != 2 {
+		return errors.New("files/cp: expected a source and a destination")
+	}
+	src, err := checkPath(r.args[0])
+	if err != nil {
+		return err
+	}
+	src = strings.TrimRight(src, "/")
+	dst, err := checkPath(r.args[1])
+	if err != nil {
+		return err
+	}
+	if dst[len(dst)-1] == '/' {
+		dst += gopath.Base(src)
+	}
+	builder, err := cidBuilder(r)
+	if err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	root, err := s.filesRoot(ctx)
+	if err != nil {
+		return err
+	}
+	nd, err := s.nodeAt(ctx, src)
+	if err != nil {
+		return fmt.Errorf("cp: cannot get node from path %s: %s", src, err)
+	}
+	if r.boolOpt("parents", false) {
+		if err := ensureParent(root, dst, builder); err != nil {
+			return err
+		}
+	}
+	if err := mfs.PutNode(root, dst, nd); err != nil {
+		return fmt.Errorf("cp: cannot put node in path %s: %s", dst, err)
+	}
+	if r.boolOpt("flush", true) {
+		if _, err := mfs.FlushPath(ctx, root, dst); err != nil {
+			return fmt.Errorf("cp: cannot flush the created file %s: %s", dst, err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) filesMv(w *responseWriter, r *request) error {
+	if len(r.args) != 2 {
+		return errors.New("files/mv: expected a source and a destination")
+	}
+	src, err := checkPath(r.args[0])
+	if err != nil {
+		return err
+	}
+	dst, err := checkPath(r.args[1])
+	if err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	root, err := s.filesRoot(ctx)
+	if err != nil {
+		return err
+	}
+	if err := mfs.Mv(root, src, dst); err != nil {
+		return err
+	}
+	if r.boolOpt("flush", true) {
+		_, err = mfs.FlushPath(ctx, root, "/")
+	}
+	return err
+}
+
+func (s *Server) filesRm(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("files/rm: expected a single path")
+	}
+	p, err := checkPath(r.args[0])
+	if err != nil {
+		return err
+	}
+	if p == "/" {
+		return errors.New("cannot delete root")
+	}
+	p = strings.TrimRight(p, "/")
+
+	root, err := s.filesRoot(r.Context())
+	if err != nil {
+		return err
+	}
+	force := r.boolOpt("force", false)
+	dirname, name := gopath.Split(p)
+	parent, err := mfs.Lookup(root, dirname)
+	if err == os.ErrNotExist && force {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("parent lookup: %s", err)
+	}
+	dir, ok := parent.(*mfs.Directory)
+	if !ok {
+		return fmt.Errorf("%s was not a directory", dirname)
+	}
+
+	child, err := dir.Child(name)
+	if err == os.ErrNotExist && force {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := child.(*mfs.Directory); ok && !r.boolOpt("recursive", false) && !force {
+		return fmt.Errorf("%s is a directory, use -r to remove directories", p)
+	}
+	if err := dir.Unlink(name); err != nil {
+		return err
+	}
+	return dir.Flush()
+}
+
+func (s *Server) filesFlush(w *responseWriter, r *request) error {
+	arg := "/"
+	if len(r.args) > 0 {
+		arg = r.args[0]
+	}
+	p, err := checkPath(arg)
+	if err != nil {
+		return err
+	}
+	ctx := r.Context()
+	root, err := s.filesRoot(ctx)
+	if err != nil {
+		return err
+	}
+	nd, err := mfs.FlushPath(ctx, root, p)
+	if err != nil {
+		return err
+	}
+	return emit(w, map[string]string{"Cid": nd.Cid().String()})
+}
@@ -0,0 +1,431 @@
+package testserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/path"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// errKeyExists is what the Kubo keystore reports when a key would be
+// overwritten.
+var errKeyExists = errors.New("key by that name already exists, refusing to overwrite")
+
+type keyOutput struct {
+	Name string
+	Id   string
+}
+
+// keyName is the IPNS name of sk, in the base36 form Kubo prints.
+func keyName(sk crypto.PrivKey) (ipns.Name, error) {
+	pid, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return ipns.Name{}, err
+	}
+	return ipns.NameFromPeer(pid), nil
+}
+
+func keyOut(name string, sk crypto.PrivKey) (keyOutput, error) {
+	n, err := keyName(sk)
+	if err != nil {
+		return keyOutput{}, err
+	}
+	return keyOutput{Name: name, Id: n.String()}, nil
+}
+
+// keystore returns the keys of the node, creating the "self" identity on
+// first use. s.mu must be held.
+func (s *Server) keystore() (map[string]crypto.PrivKey, error) {
+	if s.keys == nil {
+		self, _, err := crypto.GenerateEd25519Key(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		s.keys = map[string]crypto.PrivKey{"self": self}
+	}
+	return s.keys, nil
+}
+
+func (s *Server) keyGen(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("key/gen: argument \"name\" is required")
+	}
+	name := r.args[0]
+	if name == "self" {
+		return fmt.Errorf("cannot create key with name 'self'")
+	}
+
+	var sk crypto.PrivKey
+	var err error
+	switch typ := r.stringOpt("type", "ed25519"); typ {
+	case "ed25519":
+		sk, _, err = crypto.GenerateEd25519Key(rand.Reader)
+	case "rsa":
+		sk, _, err = crypto.GenerateRSAKeyPair(int(r.intOpt("size", 2048)), rand.Reader)
+	default:
+		return fmt.Errorf("unrecognized key type: %s", typ)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	keys, err := s.keystore()
+	if err == nil {
+		if _, ok := keys[name]; ok {
+			err = fmt.Errorf("key with name '%s' already exists", name)
+		} else {
+			keys[name] = sk
+		}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	out, err := keyOut(name, sk)
+	if err != nil {
+		return err
+	}
+	return emit(w, out)
+}
+
+// parseKey decodes an imported private key the way key/import does.
+func parseKey(data []byte, format string) (crypto.PrivKey, error) {
+	switch format {
+	case "pem-pkcs8-cleartext":
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("PEM block not found in input data:\n%s", rest)
+		}
+		if block.Type != "PRIVATE KEY" {
+			return nil, fmt.Errorf("expected PRIVATE KEY type in PEM block but got: %s", block.Type)
+		}
+		stdKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing PKCS8 format: %w", err)
+		}
+		if k, ok := stdKey.(ed25519.PrivateKey); ok {
+			stdKey = &k
+		}
+		sk, _, err := crypto.KeyPairFromStdKey(stdKey)
+		if err != nil {
+			return nil, fmt.Errorf("converting std Go key to libp2p key: %w", err)
+		}
+		return sk, nil
+	case "libp2p-protobuf-cleartext":
+		sk, err := crypto.UnmarshalPrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshall format=%s: %w", format, err)
+		}
+		return sk, nil
+	}
+	return nil, fmt.Errorf("unrecognized import format: %s", format)
+}
+
+func (s *Server) keyImport(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("key/import: argument \"name\" is required")
+	}
+	name := r.args[0]
+	if name == "self" {
+		return fmt.Errorf("cannot import key with name 'self'")
+	}
+
+	f, err := fileArg(r)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	sk, err := parseKey(data, r.stringOpt("format", "libp2p-protobuf-cleartext"))
+	if err != nil {
+		return err
+	}
+	if !r.boolOpt("allow-any-key-type", false) {
+		switch t := sk.(type) {
+		case *crypto.RsaPrivateKey, *crypto.Ed25519PrivateKey:
+		default:
+			return fmt.Errorf("key type %T is not allowed to be imported, only RSA or Ed25519;"+
+				" use flag --allow-any-key-type if you are sure of what you're doing", t)
+		}
+	}
+
+	s.mu.Lock()
+	keys, err := s.keystore()
+	if err == nil {
+		if _, ok := keys[name]; ok {
+			err = fmt.Errorf("key with name '%s' already exists", name)
+		} else {
+			keys[name] = sk
+		}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	out, err := keyOut(name, sk)
+	if err != nil {
+		return err
+	}
+	return emit(w, out)
+}
+
+func (s *Server) keyList(w *responseWriter, r *request) error {
+	s.mu.Lock()
+	keys, err := s.keystore()
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		if name != "self" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{"self"}, names...)
+
+	list := make([]keyOutput, 0, len(names))
+	for _, name := range names {
+		out, err := keyOut(name, keys[name])
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		list = append(list, out)
+	}
+	s.mu.Unlock()
+	return emit(w, map[string][]keyOutput{"Keys": list})
+}
+
+func (s *Server) keyRename(w *responseWriter, r *request) error {
+	if len(r.args) != 2 {
+		return errors.New("key/rename: expected the name and the new name")
+	}
+	oldName, newName := r.args[0], r.args[1]
+	switch {
+	case oldName == "self":
+		return fmt.Errorf("cannot rename key with name 'self'")
+	case newName == "self":
+		return fmt.Errorf("cannot overwrite key with name 'self'")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, err := s.keystore()
+	if err != nil {
+		return err
+	}
+	sk, ok := keys[oldName]
+	if !ok {
+		return fmt.Errorf("no key named %s was found", oldName)
+	}
+	_, overwrite := keys[newName]
+	if overwrite && !r.boolOpt("force", false) {
+		return errKeyExists
+	}
+	delete(keys, oldName)
+	keys[newName] = sk
+
+	out, err := keyOut(newName, sk)
+	if err != nil {
+		return err
+	}
+	return emit(w, map[string]interface{}{
+		"Was":       oldName,
+		"Now":       newName,
+		"Id":        out.Id,
+		"Overwrite": overwrite,
+	})
+}
+
+func (s *Server) keyRm(w *responseWriter, r *request) error {
+	if len(r.args) == 0 {
+		return errors.New("key/rm: argument \"name\" is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, err := s.keystore()
+	if err != nil {
+		return err
+	}
+	list := make([]keyOutput, 0, len(r.args))
+	for _, name := range r.args {
+		if name == "self" {
+			return fmt.Errorf("cannot remove key with name 'self'")
+		}
+		sk, ok := keys[name]
+		if !ok {
+			return fmt.Errorf("no key named %s was found", name)
+		}
+		out, err := keyOut(name, sk)
+		if err != nil {
+			return err
+		}
+		delete(keys, name)
+		list = append(list, out)
+	}
+	return emit(w, map[string][]keyOutput{"Keys": list})
+}
+
+// namePublish signs a record with the chosen key and keeps it as the only
+// copy "in the routing system".
+func (s *Server) namePublish(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("name/publish: argument \"ipfs-path\" is required")
+	}
+	value, err := path.NewPath(r.args[0])
+	if err != nil {
+		return err
+	}
+	if r.boolOpt("resolve", true) && value.Namespace() == path.IPFSNamespace {
+		if _, err := s.resolve(r.Context(), value.String()); err != nil {
+			return err
+		}
+	}
+	lifetime, err := time.ParseDuration(r.stringOpt("lifetime", "48h"))
+	if err != nil {
+		return fmt.Errorf("error parsing lifetime option: %w", err)
+	}
+	ttl, err := time.ParseDuration(r.stringOpt("ttl", "1h"))
+	if err != nil {
+		return fmt.Errorf("error parsing ttl option: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, err := s.keystore()
+	if err != nil {
+		return err
+	}
+	sk, ok := keys[r.stringOpt("key", "self")]
+	if !ok {
+		return fmt.Errorf("no key by the given name was found")
+	}
+	name, err := keyName(sk)
+	if err != nil {
+		return err
+	}
+
+	// Like Kubo, the sequence number only moves when the value changes.
+	var seq uint64
+	if data, ok := s.records[name.String()]; ok {
+		prev, err := ipns.UnmarshalRecord(data)
+		if err != nil {
+			return err
+		}
+		if seq, err = prev.Sequence(); err != nil {
+			return err
+		}
+		if v, err := prev.Value(); err != nil || v.String() != value.String() {
+			seq++
+		}
+	}
+	rec, err := ipns.NewRecord(sk, value, seq, time.Now().Add(lifetime), ttl)
+	if err != nil {
+		return err
+	}
+	data, err := ipns.MarshalRecord(rec)
+	if err != nil {
+		return err
+	}
+	if s.records == nil {
+		s.records = make(map[string][]byte)
+	}
+	s.records[name.String()] = data
+
+	return emit(w, map[string]string{"Name": name.String(), "Value": value.String()})
+}
+
+// record returns the valid record published under name. s.mu must be held.
+func (s *Server) record(name ipns.Name) (*ipns.Record, bool) {
+	data, ok := s.records[name.String()]
+	if !ok {
+		return nil, false
+	}
+	rec, err := ipns.UnmarshalRecord(data)
+	if err != nil {
+		return nil, false
+	}
+	if ipns.ValidateWithName(rec, name) != nil {
+		return nil, false
+	}
+	return rec, true
+}
+
+func (s *Server) nameResolve(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("name/resolve: expected a single name")
+	}
+	p := r.args[0]
+	if !strings.HasPrefix(p, "/ipns/") {
+		p = "/ipns/" + p
+	}
+
+	depth := 1
+	if r.boolOpt("recursive", true) {
+		depth = 32
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < depth && strings.HasPrefix(p, "/ipns/"); i++ {
+		name, err := ipns.NameFromString(p)
+		if err != nil {
+			return fmt.Errorf("could not resolve name: %w", err)
+		}
+		rec, ok := s.record(name)
+		if !ok {
+			return fmt.Errorf("could not resolve name")
+		}
+		value, err := rec.Value()
+		if err != nil {
+			return err
+		}
+		// Keep the rest of the path below the name.
+		p = value.String() + strings.TrimPrefix(strings.TrimPrefix(p, "/ipns/"), name.String())
+	}
+	return emit(w, map[string]string{"Path": p})
+}
+
+// routingValue is the type of the routing query event carrying a value.
+const routingValue = 5
+
+func (s *Server) routingGet(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("routing/get: argument \"key\" is required")
+	}
+	name, err := ipns.NameFromString(r.args[0])
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	data, ok := s.records[name.String()]
+	s.mu.Unlock()
+	if !ok {
+		return errors.New("routing: not found")
+	}
+	return emit(w, map[string]interface{}{
+		"Extra": base64.StdEncoding.EncodeToString(data),
+		"Type":  routingValue,
+	})
+}
@@ -0,0 +1,72 @@
+package testserver
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+func (s *Server) version(w *responseWriter, r *request) error {
+	return emit(w, map[string]string{
+		"Version": s.Version,
+		"Commit":  "",
+		"Repo":    "15",
+		"System":  runtime.GOARCH + "/" + runtime.GOOS,
+		"Golang":  runtime.Version(),
+	})
+}
+
+type swarmPeer struct {
+	Addr    string
+	Peer    string
+	Latency string
+	Muxer   string
+}
+
+// splitPeer cuts a multiaddr into its transport address and peer ID.
+func splitPeer(addr string) (string, string, bool) {
+	i := strings.LastIndex(addr, "/p2p/")
+	if i < 0 || i+len("/p2p/") == len(addr) {
+		return "", "", false
+	}
+	return addr[:i], addr[i+len("/p2p/"):], true
+}
+
+func (s *Server) swarmPeers(w *responseWriter, r *request) error {
+	s.mu.Lock()
+	peers := make([]swarmPeer, 0, len(s.peers))
+	for _, p := range s.peers {
+		addr, id, _ := splitPeer(p)
+		peers = append(peers, swarmPeer{Addr: addr, Peer: id})
+	}
+	s.mu.Unlock()
+	return emit(w, map[string][]swarmPeer{"Peers": peers})
+}
+
+func (s *Server) swarmConnect(w *responseWriter, r *request) error {
+	if len(r.args) == 0 {
+		return fmt.Errorf("argument \"address\" is required")
+	}
+
+	var out []string
+	for _, a := range r.args {
+		_, id, ok := splitPeer(a)
+		if !ok {
+			return fmt.Errorf("%s: peer address must end with /p2p/<peer id>", a)
+		}
+		out = append(out, "connect "+id+" success")
+	}
+
+	s.mu.Lock()
+	for _, a := range r.args {
+		known := false
+		for _, p := range s.peers {
+			known = known || p == a
+		}
+		if !known {
+			s.peers = append(s.peers, a)
+		}
+	}
+	s.mu.Unlock()
+	return emit(w, map[string][]string{"Strings": out})
+}
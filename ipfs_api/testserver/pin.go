@@ -0,0 +1,269 @@
+package testserver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+)
+
+// resolveCids resolves every argument of r to the CID of its last node.
+func (s *Server) resolveCids(r *request) ([]cid.Cid, error) {
+	if len(r.args) == 0 {
+		return nil, errors.New("argument \"ipfs-path\" is required")
+	}
+	out := make([]cid.Cid, 0, len(r.args))
+	for _, p := range r.args {
+		nd, err := s.resolve(r.Context(), p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, nd.Cid())
+	}
+	return out, nil
+}
+
+func cidStrings(cids []cid.Cid) []string {
+	out := make([]string, 0, len(cids))
+	for _, c := range cids {
+		out = append(out, c.String())
+	}
+	return out
+}
+
+func (s *Server) pinAdd(w *responseWriter, r *request) error {
+	cids, err := s.resolveCids(r)
+	if err != nil {
+		return err
+	}
+
+	typ := "direct"
+	recursive := r.boolOpt("recursive", true)
+	if recursive {
+		typ = "recursive"
+	}
+	for _, c := range cids {
+		if !recursive {
+			continue
+		}
+		n, err := s.complete(r.Context(), c)
+		if err != nil {
+			return fmt.Errorf("pin: %w", err)
+		}
+		if r.boolOpt("progress", false) {
+			if err := emit(w, map[string]int{"Progress": n}); err != nil {
+				return err
+			}
+		}
+	}
+
+	name := r.stringOpt("name", "")
+	s.mu.Lock()
+	for _, c := range cids {
+		if p, ok := s.pins[c]; ok && p.typ == "recursive" && !recursive {
+			s.mu.Unlock()
+			return fmt.Errorf("pin: %s already pinned recursively", c)
+		}
+		s.pins[c] = pinEntry{typ: typ, name: name}
+	}
+	s.mu.Unlock()
+	return emit(w, map[string][]string{"Pins": cidStrings(cids)})
+}
+
+func (s *Server) pinRm(w *responseWriter, r *request) error {
+	cids, err := s.resolveCids(r)
+	if err != nil {
+		return err
+	}
+
+	recursive := r.boolOpt("recursive", true)
+	s.mu.Lock()
+	for _, c := range cids {
+		p, ok := s.pins[c]
+		if !ok {
+			s.mu.Unlock()
+			return errors.New("not pinned or pinned indirectly")
+		}
+		if p.typ == "recursive" && !recursive {
+			s.mu.Unlock()
+			return fmt.Errorf("%s is pinned recursively", c)
+		}
+	}
+	for _, c := range cids {
+		delete(s.pins, c)
+	}
+	s.mu.Unlock()
+	return emit(w, map[string][]string{"Pins": cidStrings(cids)})
+}
+
+type pinLsEntry struct {
+	Cid  string
+	Type string
+	Name string `json:",omitempty"`
+}
+
+// pinList returns the pins of the node sorted by CID, indirect pins being
+// the blocks reachable from a recursive pin.
+func (s *Server) pinList(r *request) ([]pinLsEntry, error) {
+	s.mu.Lock()
+	pins := make(map[cid.Cid]pinEntry, len(s.pins))
+	for c, p := range s.pins {
+		pins[c] = p
+	}
+	s.mu.Unlock()
+
+	var out []pinLsEntry
+	for c, p := range pins {
+		out = append(out, pinLsEntry{Cid: c.String(), Type: p.typ, Name: p.name})
+	}
+
+	indirect := make(map[cid.Cid]bool)
+	for c, p := range pins {
+		if p.typ != "recursive" {
+			continue
+		}
+		err := s.walk(r.Context(), c, func(child cid.Cid, _ format.Node, err error) error {
+			if _, ok := pins[child]; !ok && err == nil {
+				indirect[child] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for c := range indirect {
+		out = append(out, pinLsEntry{Cid: c.String(), Type: "indirect"})
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Cid < out[j].Cid })
+	return out, nil
+}
+
+func (s *Server) pinLs(w *responseWriter, r *request) error {
+	typ := r.stringOpt("type", "all")
+	switch typ {
+	case "all", "direct", "indirect", "recursive":
+	default:
+		return fmt.Errorf("invalid type '%s', must be one of {direct, indirect, recursive, all}", typ)
+	}
+
+	all, err := s.pinList(r)
+	if err != nil {
+		return err
+	}
+	var list []pinLsEntry
+	if len(r.args) > 0 {
+		cids, err := s.resolveCids(r)
+		if err != nil {
+			return err
+		}
+		for i, c := range cids {
+			found := false
+			for _, e := range all {
+				if e.Cid == c.String() && (typ == "all" || e.Type == typ) {
+					list = append(list, e)
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("path '%s' is not pinned", r.args[i])
+			}
+		}
+	} else {
+		for _, e := range all {
+			if typ == "all" || e.Type == typ {
+				list = append(list, e)
+			}
+		}
+	}
+
+	names := r.boolOpt("names", false)
+	if !names {
+		for i := range list {
+			list[i].Name = ""
+		}
+	}
+	if r.boolOpt("stream", false) {
+		for _, e := range list {
+			if err := emit(w, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	keys := make(map[string]interface{}, len(list))
+	for _, e := range list {
+		keys[e.Cid] = map[string]string{"Type": e.Type, "Name": e.Name}
+	}
+	return emit(w, map[string]interface{}{"Keys": keys})
+}
+
+func (s *Server) pinUpdate(w *responseWriter, r *request) error {
+	if len(r.args) != 2 {
+		return errors.New("pin/update: expected the from and to paths")
+	}
+	cids, err := s.resolveCids(r)
+	if err != nil {
+		return err
+	}
+	from, to := cids[0], cids[1]
+
+	s.mu.Lock()
+	p, ok := s.pins[from]
+	s.mu.Unlock()
+	if !ok || p.typ != "recursive" {
+		return errors.New("'from' cid was not recursively pinned already")
+	}
+	if _, err := s.complete(r.Context(), to); err != nil {
+		return fmt.Errorf("pin: %w", err)
+	}
+
+	s.mu.Lock()
+	s.pins[to] = p
+	if r.boolOpt("unpin", true) && !from.Equals(to) {
+		delete(s.pins, from)
+	}
+	s.mu.Unlock()
+	return emit(w, map[string][]string{"Pins": cidStrings(cids)})
+}
+
+func (s *Server) pinVerify(w *responseWriter, r *request) error {
+	s.mu.Lock()
+	var roots []cid.Cid
+	for c, p := range s.pins {
+		if p.typ == "recursive" {
+			roots = append(roots, c)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(roots, func(i, j int) bool { return roots[i].String() < roots[j].String() })
+
+	verbose := r.boolOpt("verbose", false)
+	for _, root := range roots {
+		var bad []map[string]string
+		err := s.walk(r.Context(), root, func(c cid.Cid, _ format.Node, err error) error {
+			if err != nil {
+				bad = append(bad, map[string]string{"Cid": c.String(), "Err": err.Error()})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(bad) == 0 && !verbose {
+			continue
+		}
+		out := map[string]interface{}{"Cid": root.String(), "Ok": len(bad) == 0}
+		if len(bad) > 0 {
+			out["BadNodes"] = bad
+		}
+		if err := emit(w, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
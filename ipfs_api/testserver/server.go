@@ -0,0 +1,277 @@
+// Package testserver is an in-process stand-in for the Kubo RPC API, so code
+// using ipfs_api can be tested without a running node. It keeps its blocks in
+// memory and implements the commands the SDK relies on; faults such as
+// latency, error statuses, truncated streams and X-Stream-Error trailers can
+// be injected per command.
+package testserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/boxo/blockservice"
+	"github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	"github.com/ipfs/boxo/mfs"
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/urchinfs/go-urchin2-sdk/car"
+)
+
+const apiPrefix = "/api/v0/"
+
+type pinEntry struct {
+	typ  string
+	name string
+}
+
+// request is an RPC call: its positional arguments and options.
+type request struct {
+	*http.Request
+	args []string
+	opts url.Values
+}
+
+func (r *request) option(name string) (string, bool) {
+	v, ok := r.opts[name]
+	if !ok || len(v) == 0 {
+		return "", false
+	}
+	return v[0], true
+}
+
+func (r *request) stringOpt(name, def string) string {
+	if v, ok := r.option(name); ok {
+		return v
+	}
+	return def
+}
+
+func (r *request) boolOpt(name string, def bool) bool {
+	v, ok := r.option(name)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+func (r *request) intOpt(name string, def int64) int64 {
+	v, ok := r.option(name)
+	if !ok {
+		return def
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+type command func(w *responseWriter, r *request) error
+
+// Server is a fake Kubo node. The zero value is not usable, see New.
+type Server struct {
+	// Version is reported by the version command.
+	Version string
+
+	srv      *httptest.Server
+	bs       blockstore.Blockstore
+	dag      format.DAGService
+	commands map[string]command
+
+	mfsOnce sync.Once
+	mfsRoot *mfs.Root
+	mfsErr  error
+
+	mu       sync.Mutex
+	pins     map[cid.Cid]pinEntry
+	peers    []string
+	keys     map[string]crypto.PrivKey
+	records  map[string][]byte
+	faults   []*faultRule
+	requests map[string]int
+}
+
+// New starts a fake node listening on a random local port. Close it when
+// done.
+func New() *Server {
+	s := NewUnstarted()
+	s.srv = httptest.NewServer(s)
+	return s
+}
+
+// NewUnstarted creates a fake node to be served by the caller, for instance
+// with httptest.NewTLSServer.
+func NewUnstarted() *Server {
+	bs := car.NewMemBlockstore()
+	s := &Server{
+		Version:  "0.28.0",
+		bs:       bs,
+		dag:      merkledag.NewDAGService(blockservice.New(bs, offline.Exchange(bs))),
+		pins:     make(map[cid.Cid]pinEntry),
+		requests: make(map[string]int),
+	}
+	s.commands = map[string]command{
+		"version":       s.version,
+		"add":           s.add,
+		"get":           s.get,
+		"cat":           s.cat,
+		"ls":            s.ls,
+		"files/mkdir":   s.filesMkdir,
+		"files/write":   s.filesWrite,
+		"files/read":    s.filesRead,
+		"files/ls":      s.filesLs,
+		"files/stat":    s.filesStat,
+		"files/cp":      s.filesCp,
+		"files/mv":      s.filesMv,
+		"files/rm":      s.filesRm,
+		"files/flush":   s.filesFlush,
+		"block/get":     s.blockGet,
+		"block/stat":    s.blockStat,
+		"block/put":     s.blockPut,
+		"block/rm":      s.blockRm,
+		"dag/import":    s.dagImport,
+		"dag/export":    s.dagExport,
+		"dag/get":       s.dagGet,
+		"dag/put":       s.dagPut,
+		"dag/resolve":   s.dagResolve,
+		"dag/stat":      s.dagStat,
+		"swarm/peers":   s.swarmPeers,
+		"swarm/connect": s.swarmConnect,
+		"pin/add":       s.pinAdd,
+		"pin/rm":        s.pinRm,
+		"pin/ls":        s.pinLs,
+		"pin/update":    s.pinUpdate,
+		"pin/verify":    s.pinVerify,
+		"key/gen":       s.keyGen,
+		"key/import":    s.keyImport,
+		"key/list":      s.keyList,
+		"key/rename":    s.keyRename,
+		"key/rm":        s.keyRm,
+		"name/publish":  s.namePublish,
+		"name/resolve":  s.nameResolve,
+		"routing/get":   s.routingGet,
+	}
+	return s
+}
+
+// URL is the address to give to ipfs_api.NewClient.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Close stops a server started with New.
+func (s *Server) Close() {
+	if s.srv != nil {
+		s.srv.Close()
+	}
+	if s.mfsRoot != nil {
+		s.mfsRoot.Close()
+	}
+}
+
+// Blockstore gives direct access to the blocks of the node, to seed content
+// or to check what an upload stored.
+func (s *Server) Blockstore() blockstore.Blockstore {
+	return s.bs
+}
+
+// AddPeer makes swarm/peers report a connection to addr, a multiaddr ending
+// with /p2p/<peer id>.
+func (s *Server) AddPeer(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peers = append(s.peers, addr)
+}
+
+// Requests returns the number of calls received for command, including the
+// ones answered with an injected fault.
+func (s *Server) Requests(command string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[command]
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	cmd, ok := s.commands[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, fmt.Sprintf("405 - Method Not Allowed: %s", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests[name]++
+	fault := s.takeFault(name)
+	s.mu.Unlock()
+
+	rw := &responseWriter{w: w}
+	if fault != nil && !fault.apply(rw, r) {
+		return
+	}
+
+	// Like Kubo, keep reading uploads while streaming the output, so
+	// progress can be reported before the body ends.
+	_ = http.NewResponseController(w).EnableFullDuplex()
+
+	query := r.URL.Query()
+	req := &request{Request: r, args: query["arg"], opts: query}
+	// Errors found after the output started are reported like Kubo does,
+	// in the X-Stream-Error trailer.
+	w.Header().Set("Trailer", "X-Stream-Error")
+	if err := cmd(rw, req); err != nil {
+		if rw.written == 0 {
+			writeError(rw, http.StatusInternalServerError, err.Error())
+		} else {
+			w.Header().Set("X-Stream-Error", err.Error())
+		}
+		return
+	}
+	if fault != nil && fault.StreamError != "" {
+		w.Header().Set("X-Stream-Error", fault.StreamError)
+	}
+}
+
+// writeError answers with an RPC error in the JSON form Kubo uses.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"Message": msg,
+		"Code":    0,
+		"Type":    "error",
+	})
+}
+
+// emit writes one JSON object of a command output and flushes it, so
+// streamed outputs reach the client as they are produced.
+func emit(w *responseWriter, v interface{}) error {
+	if w.written == 0 {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
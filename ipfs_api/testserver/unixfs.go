@@ -0,0 +1,525 @@
+package testserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	gopath "path"
+	"strings"
+
+	"github.com/ipfs/boxo/blockservice"
+	"github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	unixfile "github.com/ipfs/boxo/ipld/unixfs/file"
+	uio "github.com/ipfs/boxo/ipld/unixfs/io"
+	unixfspb "github.com/ipfs/boxo/ipld/unixfs/pb"
+	"github.com/ipfs/boxo/path"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-cidutil"
+	format "github.com/ipfs/go-ipld-format"
+	coreiface "github.com/ipfs/kubo/core/coreiface"
+	"github.com/ipfs/kubo/core/coreunix"
+	mc "github.com/multiformats/go-multicodec"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/urchinfs/go-urchin2-sdk/car"
+)
+
+// splitPath parses an /ipfs/ path, or a bare CID followed by a path, into its
+// root and the names below it.
+func splitPath(p string) (cid.Cid, []string, error) {
+	if strings.HasPrefix(p, "/ipns/") {
+		return cid.Undef, nil, fmt.Errorf("%s: ipns paths are not supported by the test server", p)
+	}
+	p = strings.TrimPrefix(p, "/ipfs/")
+	segs := strings.Split(strings.Trim(p, "/"), "/")
+	c, err := cid.Decode(segs[0])
+	if err != nil {
+		return cid.Undef, nil, fmt.Errorf("invalid path %q: %w", p, err)
+	}
+
+	var names []string
+	for _, s := range segs[1:] {
+		if s != "" {
+			names = append(names, s)
+		}
+	}
+	return c, names, nil
+}
+
+// getNode reads a node from the blockstore. There is no network to fetch
+// from, so a missing block fails right away like on an offline node.
+func (s *Server) getNode(ctx context.Context, c cid.Cid) (format.Node, error) {
+	return s.dag.Get(ctx, c)
+}
+
+// resolve walks the UnixFS path p down to its last node.
+func (s *Server) resolve(ctx context.Context, p string) (format.Node, error) {
+	c, names, err := splitPath(p)
+	if err != nil {
+		return nil, err
+	}
+	nd, err := s.getNode(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		dir, err := uio.NewDirectoryFromNode(s.dag, nd)
+		if err != nil {
+			return nil, fmt.Errorf("no link named %q under %s", name, nd.Cid())
+		}
+		child, err := dir.Find(ctx, name)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no link named %q under %s", name, nd.Cid())
+		}
+		if err != nil {
+			return nil, err
+		}
+		nd = child
+	}
+	return nd, nil
+}
+
+// unixfsInfo is what ls and files/stat report about a node.
+type unixfsInfo struct {
+	typ    unixfspb.Data_DataType
+	size   uint64
+	target string
+}
+
+func describe(nd format.Node) (unixfsInfo, error) {
+	switch n := nd.(type) {
+	case *merkledag.RawNode:
+		return unixfsInfo{typ: ft.TFile, size: uint64(len(n.RawData()))}, nil
+	case *merkledag.ProtoNode:
+		fsn, err := ft.FSNodeFromBytes(n.Data())
+		if err != nil {
+			return unixfsInfo{}, err
+		}
+		info := unixfsInfo{typ: fsn.Type(), size: fsn.FileSize()}
+		if fsn.Type() == ft.TSymlink {
+			info.target = string(fsn.Data())
+		}
+		return info, nil
+	}
+	return unixfsInfo{}, fmt.Errorf("%s is not a unixfs node", nd.Cid())
+}
+
+// newAdder builds a UnixFS adder configured from the options of the add
+// command, the same way Kubo does.
+func newAdder(ctx context.Context, r *request, dag format.DAGService) (*coreunix.Adder, error) {
+	adder, err := coreunix.NewAdder(ctx, nil, nil, dag)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := r.stringOpt("hash", "sha2-256")
+	code, ok := mh.Names[hash]
+	if !ok {
+		return nil, fmt.Errorf("unrecognized hash function: %q", hash)
+	}
+	version := r.intOpt("cid-version", 0)
+	// Like Kubo, CIDv0 is only kept when the hash allows it.
+	if code != mh.SHA2_256 {
+		version = 1
+	}
+	prefix, err := merkledag.PrefixForCidVersion(int(version))
+	if err != nil {
+		return nil, err
+	}
+	prefix.MhType = code
+	prefix.MhLength = -1
+	adder.CidBuilder = prefix
+	if r.boolOpt("inline", false) {
+		adder.CidBuilder = cidutil.InlineBuilder{Builder: prefix, Limit: int(r.intOpt("inline-limit", 32))}
+	}
+
+	adder.RawLeaves = r.boolOpt("raw-leaves", version == 1)
+	adder.Chunker = r.stringOpt("chunker", "size-262144")
+	adder.Trickle = r.boolOpt("trickle", false)
+	// Pins are kept by the server itself, see add.
+	adder.Pin = false
+	return adder, nil
+}
+
+func (s *Server) add(w *responseWriter, r *request) error {
+	mediatype, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(mediatype, "multipart/") {
+		return fmt.Errorf("add: expected a multipart body, got %s", mediatype)
+	}
+	dir, err := files.NewFileFromPartReader(multipart.NewReader(r.Body, params["boundary"]), mediatype)
+	if err != nil {
+		return err
+	}
+
+	onlyHash := r.boolOpt("only-hash", false)
+	dag := s.dag
+	if onlyHash {
+		bs := car.NewMemBlockstore()
+		dag = merkledag.NewDAGService(blockservice.New(bs, offline.Exchange(bs)))
+	}
+
+	ctx := r.Context()
+	wrap := r.boolOpt("wrap-with-directory", false)
+	it := dir.Entries()
+	for it.Next() {
+		name := it.Name()
+		var target files.Node = it.Node()
+		if wrap {
+			target = files.NewSliceDirectory([]files.DirEntry{files.FileEntry(name, target)})
+		}
+
+		adder, err := newAdder(ctx, r, dag)
+		if err != nil {
+			return err
+		}
+		adder.Progress = r.boolOpt("progress", false)
+		events := make(chan interface{}, 16)
+		adder.Out = events
+		done := make(chan error, 1)
+		var root format.Node
+		go func() {
+			var err error
+			root, err = adder.AddAllAndPin(ctx, target)
+			close(events)
+			done <- err
+		}()
+
+		var werr error
+		for v := range events {
+			ev, ok := v.(*coreiface.AddEvent)
+			if !ok || werr != nil {
+				continue
+			}
+			// Named like Kubo does: an unwrapped file after itself, other
+			// entries by their path under the added entry.
+			entryName := ev.Name
+			if _, dir := target.(files.Directory); !dir {
+				entryName = name
+			} else if !wrap {
+				entryName = gopath.Join(name, ev.Name)
+			}
+			// Progress events carry no path, only the bytes read so far.
+			out := map[string]interface{}{"Name": entryName}
+			if (ev.Path == path.ImmutablePath{}) {
+				out["Bytes"] = ev.Bytes
+			} else {
+				out["Hash"] = ev.Path.RootCid().String()
+				out["Size"] = ev.Size
+			}
+			werr = emit(w, out)
+		}
+		if err := <-done; err != nil {
+			return err
+		}
+		if werr != nil {
+			return werr
+		}
+
+		if r.boolOpt("pin", true) && !onlyHash {
+			s.mu.Lock()
+			if _, ok := s.pins[root.Cid()]; !ok {
+				s.pins[root.Cid()] = pinEntry{typ: "recursive"}
+			}
+			s.mu.Unlock()
+		}
+	}
+	return it.Err()
+}
+
+func (s *Server) get(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("get: expected a single path")
+	}
+	if r.boolOpt("compress", false) {
+		return errors.New("get: compression is not supported by the test server")
+	}
+
+	ctx := r.Context()
+	nd, err := s.resolve(ctx, r.args[0])
+	if err != nil {
+		return err
+	}
+	f, err := unixfile.NewUnixfsFile(ctx, s.dag, nd)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	tw, err := files.NewTarWriter(w)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteFile(f, gopath.Base(strings.TrimSuffix(r.args[0], "/"))); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func (s *Server) cat(w *responseWriter, r *request) error {
+	if len(r.args) == 0 {
+		return errors.New("cat: argument \"ipfs-path\" is required")
+	}
+
+	ctx := r.Context()
+	readers := make([]io.Reader, 0, len(r.args))
+	for _, p := range r.args {
+		nd, err := s.resolve(ctx, p)
+		if err != nil {
+			return err
+		}
+		node, err := unixfile.NewUnixfsFile(ctx, s.dag, nd)
+		if err != nil {
+			return err
+		}
+		f, ok := node.(files.File)
+		if !ok {
+			return errors.New("this dag node is a directory")
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	offset := r.intOpt("offset", 0)
+	if offset < 0 {
+		return fmt.Errorf("cannot specify negative offset")
+	}
+	var rd io.Reader
+	if len(readers) == 1 {
+		if _, err := readers[0].(io.Seeker).Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		rd = readers[0]
+	} else {
+		rd = io.MultiReader(readers...)
+		if _, err := io.CopyN(io.Discard, rd, offset); err != nil && err != io.EOF {
+			return err
+		}
+	}
+	if length, ok := r.option("length"); ok {
+		n := r.intOpt("length", 0)
+		if n < 0 {
+			return fmt.Errorf("cannot specify negative length %s", length)
+		}
+		rd = io.LimitReader(rd, n)
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	_, err := io.Copy(w, rd)
+	return err
+}
+
+type lsLink struct {
+	Name   string
+	Hash   string
+	Size   uint64
+	Type   int32
+	Target string
+}
+
+type lsObject struct {
+	Hash  string
+	Links []lsLink
+}
+
+func (s *Server) ls(w *responseWriter, r *request) error {
+	if len(r.args) == 0 {
+		return errors.New("ls: argument \"ipfs-path\" is required")
+	}
+
+	ctx := r.Context()
+	stream := r.boolOpt("stream", false)
+	var objects []lsObject
+	for _, p := range r.args {
+		nd, err := s.resolve(ctx, p)
+		if err != nil {
+			return err
+		}
+
+		obj := lsObject{Hash: p, Links: []lsLink{}}
+		dir, err := uio.NewDirectoryFromNode(s.dag, nd)
+		if err == nil {
+			links, err := dir.Links(ctx)
+			if err != nil {
+				return err
+			}
+			for _, l := range links {
+				child, err := s.getNode(ctx, l.Cid)
+				if err != nil {
+					return err
+				}
+				info, err := describe(child)
+				if err != nil {
+					return err
+				}
+				link := lsLink{Name: l.Name, Hash: l.Cid.String(), Size: info.size, Type: int32(info.typ), Target: info.target}
+				if stream {
+					if err := emit(w, map[string][]lsObject{"Objects": {{Hash: p, Links: []lsLink{link}}}}); err != nil {
+						return err
+					}
+					continue
+				}
+				obj.Links = append(obj.Links, link)
+			}
+		}
+		if !stream {
+			objects = append(objects, obj)
+		}
+	}
+	if stream {
+		return nil
+	}
+	return emit(w, map[string][]lsObject{"Objects": objects})
+}
+
+func (s *Server) blockGet(w *responseWriter, r *request) error {
+	if len(r.args) != 1 {
+		return errors.New("block/get: expected a single cid")
+	}
+	c, _, err := splitPath(r.args[0])
+	if err != nil {
+		return err
+	}
+	blk, err := s.bs.Get(r.Context(), c)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(blk.RawData())
+	return err
+}
+
+func (s *Server) blockStat(w *responseWriter, r *request) error {
+	if len(r.args) == 0 {
+		return errors.New("block/stat: argument \"cid\" is required")
+	}
+	for _, a := range r.args {
+		c, _, err := splitPath(a)
+		if err != nil {
+			return err
+		}
+		size, err := s.bs.GetSize(r.Context(), c)
+		if err != nil {
+			return err
+		}
+		if err := emit(w, map[string]interface{}{"Key": c.String(), "Size": size}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) blockPut(w *responseWriter, r *request) error {
+	mediatype, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	if mediatype != "multipart/form-data" {
+		return fmt.Errorf("block/put: expected a multipart body, got %s", mediatype)
+	}
+
+	var codec mc.Code
+	if err := codec.Set(r.stringOpt("cid-codec", "raw")); err != nil {
+		return err
+	}
+	hash := r.stringOpt("mhtype", "sha2-256")
+	code, ok := mh.Names[hash]
+	if !ok {
+		return fmt.Errorf("unrecognized multihash function: %s", hash)
+	}
+	prefix := cid.Prefix{Version: 1, Codec: uint64(codec), MhType: code, MhLength: int(r.intOpt("mhlen", -1))}
+
+	ctx := r.Context()
+	mpr := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := mpr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return err
+		}
+		if len(data) > 1<<20 && !r.boolOpt("allow-big-block", false) {
+			return errors.New("produced block is over 1MiB: big blocks can't be exchanged with other peers")
+		}
+
+		c, err := prefix.Sum(data)
+		if err != nil {
+			return err
+		}
+		blk, err := blocks.NewBlockWithCid(data, c)
+		if err != nil {
+			return err
+		}
+		if err := s.bs.Put(ctx, blk); err != nil {
+			return err
+		}
+		if r.boolOpt("pin", false) {
+			s.mu.Lock()
+			s.pins[c] = pinEntry{typ: "recursive"}
+			s.mu.Unlock()
+		}
+		if err := emit(w, map[string]interface{}{"Key": c.String(), "Size": len(data)}); err != nil {
+			return err
+		}
+	}
+}
+
+// blockRm reports the blocks it cannot remove, pinned or missing ones, in
+// its output like Kubo rather than failing the whole command.
+func (s *Server) blockRm(w *responseWriter, r *request) error {
+	if len(r.args) == 0 {
+		return errors.New("block/rm: argument \"cid\" is required")
+	}
+	pins, err := s.pinList(r)
+	if err != nil {
+		return err
+	}
+	pinned := make(map[string]string, len(pins))
+	for _, p := range pins {
+		pinned[p.Cid] = p.Type
+	}
+
+	ctx := r.Context()
+	force := r.boolOpt("force", false)
+	for _, a := range r.args {
+		c, _, err := splitPath(a)
+		if err != nil {
+			return err
+		}
+
+		msg := ""
+		if typ, ok := pinned[c.String()]; ok {
+			msg = "pinned: " + typ
+		} else if has, err := s.bs.Has(ctx, c); err != nil {
+			msg = err.Error()
+		} else if !has && !force {
+			msg = format.ErrNotFound{Cid: c}.Error()
+		} else if err := s.bs.DeleteBlock(ctx, c); err != nil {
+			msg = err.Error()
+		}
+
+		out := map[string]string{"Hash": c.String()}
+		if msg != "" {
+			out["Error"] = msg
+		}
+		if err := emit(w, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package ipfs_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/go-cid"
+	"github.com/urchinfs/go-urchin2-sdk/car"
+	sdkcid "github.com/urchinfs/go-urchin2-sdk/cid"
+	caopts "github.com/urchinfs/go-urchin2-sdk/cid/options"
+)
+
+var (
+	ErrMissingFile = errors.New("file of the dag missing on disk")
+	ErrExtraFile   = errors.New("file on disk not in the dag")
+)
+
+// resumeLeftovers match the files a resumable Get keeps next to the ones it
+// downloads: the state file and the partial downloads of a run that failed.
+var resumeLeftovers = []string{".*.part", resumeStateFile}
+
+func isResumeLeftover(name string) bool {
+	for _, pattern := range resumeLeftovers {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// localCid computes the UnixFS CID of the file or directory at p the way it
+// was presumably added to get c: same CID version and hash function, plus
+// addOpts for any other setting. Hidden files are included, they were
+// downloaded like the others, but not the leftovers of a resumable Get.
+func localCid(ctx context.Context, p string, c cid.Cid, addOpts ...caopts.UnixfsAddOption) (cid.Cid, error) {
+	filter, err := files.NewFilter("", resumeLeftovers, true)
+	if err != nil {
+		return cid.Undef, err
+	}
+	node, err := sdkcid.AppendFile(p, true, filter)
+	if err != nil {
+		return cid.Undef, err
+	}
+	defer node.Close()
+
+	opts := []caopts.UnixfsAddOption{
+		caopts.Unixfs.Wrap(false),
+		caopts.Unixfs.CidVersion(int(c.Version())),
+		caopts.Unixfs.Hash(c.Prefix().MhType),
+	}
+	return sdkcid.AddAndBuildCid(ctx, node, append(opts, addOpts...)...)
+}
+
+// verifyTree checks the files extracted into outDir against hash. When the
+// roots differ the remote tree is walked to name the first bad file.
+func (h *HttpClient) verifyTree(ctx context.Context, hash, outDir string, addOpts []caopts.UnixfsAddOption) error {
+	root, err := cid.Decode(strings.TrimPrefix(hash, "/ipfs/"))
+	if err != nil {
+		stat, err := h.FilesStat(ctx, ipfsPath(hash))
+		if err != nil {
+			return err
+		}
+		root = stat.Cid
+	}
+
+	local, err := localCid(ctx, outDir, root, addOpts...)
+	if err != nil {
+		return err
+	}
+	if local.Equals(root) {
+		return nil
+	}
+
+	if err := h.findBadFile(ctx, hash, root, outDir, addOpts); err != nil {
+		return err
+	}
+	return &car.VerificationError{
+		Root: root,
+		Err:  fmt.Errorf("%w: files hash to %s", car.ErrRootMismatch, local),
+	}
+}
+
+// findBadFile returns a car.VerificationError for the first file of the
+// remote tree that is missing or different in outDir, or else for a file of
+// outDir the remote tree does not have.
+func (h *HttpClient) findBadFile(ctx context.Context, hash string, root cid.Cid, outDir string, addOpts []caopts.UnixfsAddOption) error {
+	stat, err := h.FilesStat(ctx, ipfsPath(hash))
+	if err != nil {
+		return err
+	}
+	if stat.Type != sdkcid.TDirectory {
+		return &car.VerificationError{Root: root, Cid: root, Path: hash, Err: car.ErrHashMismatch}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	entries, err := h.Walk(ctx, hash)
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.TrimSuffix(hash, "/") + "/"
+	remote := make(map[string]bool)
+	for entry := range entries {
+		if entry.Err != nil {
+			return entry.Err
+		}
+		rel := filepath.FromSlash(strings.TrimPrefix(entry.Path, prefix))
+		remote[rel] = true
+
+		p := filepath.Join(outDir, rel)
+		if _, err := os.Lstat(p); err != nil {
+			return &car.VerificationError{Root: root, Cid: entry.Cid, Path: entry.Path, Err: ErrMissingFile}
+		}
+		if entry.Type != sdkcid.TFile {
+			continue
+		}
+		local, err := localCid(ctx, p, entry.Cid, addOpts...)
+		if err != nil {
+			return err
+		}
+		if !local.Equals(entry.Cid) {
+			return &car.VerificationError{Root: root, Cid: entry.Cid, Path: entry.Path, Err: car.ErrHashMismatch}
+		}
+	}
+
+	return filepath.WalkDir(outDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(outDir, p)
+		if err != nil || rel == "." || remote[rel] || isResumeLeftover(d.Name()) {
+			return err
+		}
+		return &car.VerificationError{
+			Root: root,
+			Err:  fmt.Errorf("%w: %s", ErrExtraFile, filepath.Join(outDir, rel)),
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package ipfs_api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	sdkcid "github.com/urchinfs/go-urchin2-sdk/cid"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+// UnixFS node types reported by ls.
+const (
+	unixfsRaw       = 0
+	unixfsDirectory = 1
+	unixfsFile      = 2
+	unixfsSymlink   = 4
+	unixfsHAMTShard = 5
+)
+
+// FileType maps the UnixFS type of the link to cid.FileType.
+func (l *LsLink) FileType() sdkcid.FileType {
+	switch l.Type {
+	case unixfsRaw, unixfsFile:
+		return sdkcid.TFile
+	case unixfsDirectory, unixfsHAMTShard:
+		return sdkcid.TDirectory
+	case unixfsSymlink:
+		return sdkcid.TSymlink
+	default:
+		return sdkcid.TUnknown
+	}
+}
+
+// WalkEntry is a file or directory found by Walk. Path is the full path of
+// the entry, starting with the walked root. Err is set, and the channel
+// closed afterwards, when the walk fails.
+type WalkEntry struct {
+	Path   string
+	Cid    cid.Cid
+	Size   uint64
+	Type   sdkcid.FileType
+	Target string
+	Depth  int
+
+	Err error
+}
+
+type walkDir struct {
+	path  string
+	ref   string
+	depth int
+}
+
+// walker lists directories with a fixed number of workers sharing a stack
+// of directories still to list, so huge trees do not pile up goroutines.
+type walker struct {
+	h        *HttpClient
+	settings *options.WalkSettings
+	out      chan WalkEntry
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []walkDir
+	active  int
+	stopped bool
+}
+
+// Walk lists the UnixFS tree under root recursively and streams every entry
+// below it. Directories are listed concurrently, so entries of different
+// directories are interleaved.
+func (h *HttpClient) Walk(ctx context.Context, root string, opts ...options.WalkOption) (<-chan WalkEntry, error) {
+	settings, err := options.WalkOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	root = strings.TrimSuffix(root, "/")
+	w := &walker{
+		h:        h,
+		settings: settings,
+		out:      make(chan WalkEntry),
+		pending:  []walkDir{{path: root, ref: root}},
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	// Wake the workers up when the caller gives up.
+	stop := context.AfterFunc(ctx, w.stop)
+
+	var wg sync.WaitGroup
+	for i := 0; i < settings.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work(ctx)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		stop()
+		close(w.out)
+	}()
+	return w.out, nil
+}
+
+func (w *walker) stop() {
+	w.mu.Lock()
+	w.stopped = true
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+// next pops a directory to list, waiting while other workers may still
+// find some. ok is false once the walk is over.
+func (w *walker) next() (dir walkDir, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.pending) == 0 && w.active > 0 && !w.stopped {
+		w.cond.Wait()
+	}
+	if w.stopped || len(w.pending) == 0 {
+		return dir, false
+	}
+	dir = w.pending[len(w.pending)-1]
+	w.pending = w.pending[:len(w.pending)-1]
+	w.active++
+	return dir, true
+}
+
+func (w *walker) isStopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopped
+}
+
+func (w *walker) done(dirs []walkDir) {
+	w.mu.Lock()
+	w.pending = append(w.pending, dirs...)
+	w.active--
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+func (w *walker) work(ctx context.Context) {
+	for {
+		dir, ok := w.next()
+		if !ok {
+			return
+		}
+		dirs, err := w.list(ctx, dir)
+		if err != nil {
+			if ctx.Err() == nil {
+				select {
+				case w.out <- WalkEntry{Path: dir.path, Depth: dir.depth, Err: err}:
+				case <-ctx.Done():
+				}
+			}
+			w.stop()
+		}
+		w.done(dirs)
+	}
+}
+
+// list streams the links of dir and returns the subdirectories to walk.
+func (w *walker) list(ctx context.Context, dir walkDir) ([]walkDir, error) {
+	resp, err := w.h.Request("ls", dir.ref).
+		Option("stream", true).
+		Option("resolve-type", true).
+		Option("size", w.settings.Size).
+		Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	var dirs []walkDir
+	depth := dir.depth + 1
+	dec := json.NewDecoder(resp.Output)
+	for {
+		var out struct {
+			Objects []struct {
+				Links []struct {
+					LsLink
+					Target string
+				}
+			}
+		}
+		if err := dec.Decode(&out); err != nil {
+			if err == io.EOF {
+				return dirs, nil
+			}
+			return nil, err
+		}
+
+		for _, obj := range out.Objects {
+			for _, link := range obj.Links {
+				entry := WalkEntry{
+					Path:   dir.path + "/" + link.Name,
+					Size:   link.Size,
+					Type:   link.FileType(),
+					Target: link.Target,
+					Depth:  depth,
+				}
+				if entry.Cid, err = cid.Decode(link.Hash); err != nil {
+					return nil, err
+				}
+				if w.settings.Filter != nil && !w.settings.Filter(entry.Path, entry.Type) {
+					continue
+				}
+				// Another worker failed, nothing may follow its error.
+				if w.isStopped() {
+					return nil, nil
+				}
+
+				select {
+				case w.out <- entry:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+
+				if entry.Type == sdkcid.TDirectory && (w.settings.MaxDepth <= 0 || depth < w.settings.MaxDepth) {
+					dirs = append(dirs, walkDir{path: entry.Path, ref: entry.Cid.String(), depth: depth})
+				}
+			}
+		}
+	}
+}
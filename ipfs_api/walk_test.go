@@ -0,0 +1,110 @@
+package ipfs_api
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	sdkcid "github.com/urchinfs/go-urchin2-sdk/cid"
+	caopts "github.com/urchinfs/go-urchin2-sdk/cid/options"
+	"github.com/urchinfs/go-urchin2-sdk/ipfs_api/options"
+)
+
+func walkPaths(t *testing.T, c *HttpClient, root string, opts ...options.WalkOption) ([]string, error) {
+	t.Helper()
+	entries, err := c.Walk(context.Background(), root, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for entry := range entries {
+		if entry.Err != nil {
+			return paths, entry.Err
+		}
+		paths = append(paths, entry.Path[len(root):])
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func TestWalk(t *testing.T) {
+	c, _ := newTestClient(t)
+	root := addTree(t, c).String()
+
+	tests := []struct {
+		name string
+		opts []options.WalkOption
+		want []string
+	}{
+		{
+			name: "whole tree",
+			want: []string{"/.hidden", "/a.txt", "/sub", "/sub/.config", "/sub/b.bin"},
+		},
+		{
+			name: "max depth",
+			opts: []options.WalkOption{options.Walk.MaxDepth(1)},
+			want: []string{"/.hidden", "/a.txt", "/sub"},
+		},
+		{
+			name: "no max depth",
+			opts: []options.WalkOption{options.Walk.MaxDepth(0)},
+			want: []string{"/.hidden", "/a.txt", "/sub", "/sub/.config", "/sub/b.bin"},
+		},
+		{
+			name: "filter",
+			opts: []options.WalkOption{options.Walk.Filter(func(path string, typ sdkcid.FileType) bool {
+				return typ == sdkcid.TDirectory || strings.HasSuffix(path, ".bin")
+			})},
+			want: []string{"/sub", "/sub/b.bin"},
+		},
+		{
+			name: "single worker",
+			opts: []options.WalkOption{options.Walk.Concurrency(1)},
+			want: []string{"/.hidden", "/a.txt", "/sub", "/sub/.config", "/sub/b.bin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := walkPaths(t, c, root, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walk = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkSizes(t *testing.T) {
+	c, _ := newTestClient(t)
+	root := addTree(t, c).String()
+
+	entries, err := c.Walk(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for entry := range entries {
+		if entry.Path == root+"/sub/b.bin" && (entry.Size != 600000 || entry.Type != sdkcid.TFile || entry.Depth != 2) {
+			t.Errorf("entry = %+v, want a 600000 byte file at depth 2", entry)
+		}
+	}
+}
+
+func TestWalkMissingRoot(t *testing.T) {
+	c, _ := newTestClient(t)
+	res, err := c.AddBytes(context.Background(), "f", []byte("not stored"),
+		caopts.Unixfs.Wrap(false), caopts.Unixfs.HashOnly(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = walkPaths(t, c, res.Root.String())
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("walk = %v, want ErrNotFound", err)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+
 	logging "github.com/ipfs/go-log"
 	"github.com/urchinfs/go-urchin2-sdk/ipfs_api"
 	"github.com/urchinfs/go-urchin2-sdk/utils"
@@ -11,15 +13,20 @@ func test() {
 	utils.InitLog()
 
 	log.Infof("start...")
-	client := ipfs_api.NewClient("192.168.242.42:5001")
-	//client := ipfs_api.NewClient("127.0.0.1:5001")
+	ctx := context.Background()
+	client, err := ipfs_api.NewClient("192.168.242.42:5001")
+	//client, err := ipfs_api.NewClient("127.0.0.1:5001")
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
 	//inputFile := "init_model.7z"
 	//outputFile := "init_model.7z.car"
 
 	//inputDir := "code"
 	//outputDir := "code.car"
 	//
-	//err := client.SwarmConnect(context.Background(), "/ip4/192.168.1.1/tcp/4001/ipfs/12D3KooWGA2h89gV5sqCH3wPxhgguzBDNsAbaHocxS6z7vmEhB1V")
+	//err := client.SwarmConnect(ctx, "/ip4/192.168.1.1/tcp/4001/ipfs/12D3KooWGA2h89gV5sqCH3wPxhgguzBDNsAbaHocxS6z7vmEhB1V")
 	//if err != nil {
 	//	log.Infof("SwarmConnect err: %v", err)
 	//	return
@@ -45,31 +52,31 @@ func test() {
 	//}
 	//log.Printf("pack car file done, CID: %s", rootCid)
 	//
-	//dagImport, err := client.DagImport(outputDir, false, false)
+	//dagImport, err := client.DagImport(ctx, outputDir, false, false)
 	//if err != nil {
 	//	log.Fatal(err)
 	//	return
 	//}
 	//log.Printf("dag import to peer done, result: %v", dagImport)
 
-	//cid, err := client.Add("R-50.pkl")
+	//added, err := client.Add(ctx, "R-50.pkl")
 	//if err != nil {
 	//	log.Fatal(err)
 	//}
-	//log.Printf("File uploaded successfully. CID: %s", cid)
+	//log.Printf("File uploaded successfully. CID: %s, wrapper: %s", added.Root, added.Wrapper)
 
-	//cid, err := client.AddDir("code")
+	//added, err := client.AddDir(ctx, "code")
 	//if err != nil {
 	//	log.Fatal(err)
 	//}
-	//log.Printf("Folder uploaded successfully. CID: %s", cid)
+	//log.Printf("Folder uploaded successfully. CID: %s, wrapper: %s", added.Root, added.Wrapper)
 
 	/*
 	*
 	* download.................................................
 	*
 	 */
-	//err := client.DagExport("QmZiM8GY7p9rCiRRJmnfa7KrXoWgJ6NbgMzvvgpz3qWUbc", "tmp.car")
+	//err := client.DagExport(ctx, "QmZiM8GY7p9rCiRRJmnfa7KrXoWgJ6NbgMzvvgpz3qWUbc", "tmp.car")
 	//if err != nil {
 	//	log.Fatal(err)
 	//	return
@@ -81,7 +88,7 @@ func test() {
 	//	return
 	//}
 
-	err := client.Get("QmU8UBwwik6iCn99VWKquPEezU9zQrJowTctpaokjtYqDa", "/root/test/down")
+	err = client.Get(ctx, "QmU8UBwwik6iCn99VWKquPEezU9zQrJowTctpaokjtYqDa", "/root/test/down")
 	if err != nil {
 		log.Fatal(err)
 		return
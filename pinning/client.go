@@ -0,0 +1,258 @@
+package pinning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log"
+)
+
+var log = logging.Logger("pinning")
+
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusPinning Status = "pinning"
+	StatusPinned  Status = "pinned"
+	StatusFailed  Status = "failed"
+)
+
+// Pin is the object a service is asked to pin.
+type Pin struct {
+	Cid     string            `json:"cid"`
+	Name    string            `json:"name,omitempty"`
+	Origins []string          `json:"origins,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+// PinStatus is the state of a pin request on the service.
+type PinStatus struct {
+	RequestID string            `json:"requestid"`
+	Status    Status            `json:"status"`
+	Created   time.Time         `json:"created"`
+	Pin       Pin               `json:"pin"`
+	Delegates []string          `json:"delegates"`
+	Info      map[string]string `json:"info,omitempty"`
+}
+
+// PinList is a page of pins. Count is the total number of matching pins,
+// which may be larger than len(Results).
+type PinList struct {
+	Count   int         `json:"count"`
+	Results []PinStatus `json:"results"`
+}
+
+// Error is a failure reported by the pinning service.
+type Error struct {
+	StatusCode int
+	Reason     string
+	Details    string
+}
+
+func (e *Error) Error() string {
+	out := fmt.Sprintf("pinning service: %d", e.StatusCode)
+	if e.Reason != "" {
+		out += ": " + e.Reason
+	}
+	if e.Details != "" {
+		out += ": " + e.Details
+	}
+	return out
+}
+
+// Client talks to a remote pinning service through the IPFS Pinning Service
+// HTTP API (https://ipfs.github.io/pinning-services-api-spec/), without going
+// through a Kubo node.
+type Client struct {
+	endpoint string
+	token    string
+	httpCli  *http.Client
+}
+
+// NewClient creates a client for the service at endpoint, e.g.
+// https://api.example.com/psa, authenticating with the access token.
+func NewClient(endpoint, token string) *Client {
+	return &Client{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		token:    token,
+		httpCli:  http.DefaultClient,
+	}
+}
+
+// SetHTTPClient replaces the client used to talk to the service.
+func (c *Client) SetHTTPClient(httpCli *http.Client) {
+	c.httpCli = httpCli
+}
+
+// Add asks the service to pin root.
+func (c *Client) Add(ctx context.Context, root cid.Cid, opts ...AddOption) (*PinStatus, error) {
+	pin, err := newPin(root, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var out PinStatus
+	if err := c.do(ctx, http.MethodPost, "/pins", nil, pin, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Ls lists the pins matching the filters, most recent first.
+func (c *Client) Ls(ctx context.Context, opts ...LsOption) (*PinList, error) {
+	options, err := LsOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	query := make(url.Values)
+	if len(options.Cids) > 0 {
+		query.Set("cid", strings.Join(options.Cids, ","))
+	}
+	if options.Name != "" {
+		query.Set("name", options.Name)
+	}
+	if options.Match != "" {
+		query.Set("match", options.Match)
+	}
+	if len(options.Status) > 0 {
+		status := make([]string, 0, len(options.Status))
+		for _, s := range options.Status {
+			status = append(status, string(s))
+		}
+		query.Set("status", strings.Join(status, ","))
+	}
+	if !options.Before.IsZero() {
+		query.Set("before", options.Before.UTC().Format(time.RFC3339))
+	}
+	if !options.After.IsZero() {
+		query.Set("after", options.After.UTC().Format(time.RFC3339))
+	}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+	if options.Meta != nil {
+		meta, err := json.Marshal(options.Meta)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("meta", string(meta))
+	}
+
+	var out PinList
+	if err := c.do(ctx, http.MethodGet, "/pins", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Get returns the status of a pin request.
+func (c *Client) Get(ctx context.Context, requestID string) (*PinStatus, error) {
+	var out PinStatus
+	if err := c.do(ctx, http.MethodGet, "/pins/"+url.PathEscape(requestID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Replace swaps the pin of an existing request for root, e.g. to move a
+// "latest" pin to a new version.
+func (c *Client) Replace(ctx context.Context, requestID string, root cid.Cid, opts ...AddOption) (*PinStatus, error) {
+	pin, err := newPin(root, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var out PinStatus
+	if err := c.do(ctx, http.MethodPost, "/pins/"+url.PathEscape(requestID), nil, pin, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Delete removes a pin request.
+func (c *Client) Delete(ctx context.Context, requestID string) error {
+	return c.do(ctx, http.MethodDelete, "/pins/"+url.PathEscape(requestID), nil, nil, nil)
+}
+
+func newPin(root cid.Cid, opts ...AddOption) (*Pin, error) {
+	options, err := AddOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pin{
+		Cid:     root.String(),
+		Name:    options.Name,
+		Origins: options.Origins,
+		Meta:    options.Meta,
+	}, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	u := c.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		e := &Error{StatusCode: resp.StatusCode}
+		var failure struct {
+			Error struct {
+				Reason  string `json:"reason"`
+				Details string `json:"details"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&failure); err != nil {
+			log.Warnf("pinning service: response (%d) unmarshall error: %s", resp.StatusCode, err)
+			e.Reason = resp.Status
+		} else {
+			e.Reason = failure.Error.Reason
+			e.Details = failure.Error.Details
+		}
+		return e
+	}
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
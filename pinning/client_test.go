@@ -0,0 +1,247 @@
+package pinning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+const testToken = "secret"
+
+// fakeService is an in-memory pinning service. Every pin is reported as
+// pinned right away.
+type fakeService struct {
+	mu      sync.Mutex
+	pins    map[string]PinStatus
+	next    int
+	lastLs  url.Values
+	created time.Time
+}
+
+func newFakeService(t *testing.T) (*Client, *fakeService) {
+	t.Helper()
+	svc := &fakeService{
+		pins:    make(map[string]PinStatus),
+		created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	ts := httptest.NewServer(svc)
+	t.Cleanup(ts.Close)
+	return NewClient(ts.URL+"/psa/", testToken), svc
+}
+
+func (s *fakeService) fail(w http.ResponseWriter, status int, reason, details string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"reason": reason, "details": details},
+	})
+}
+
+func (s *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		s.fail(w, http.StatusUnauthorized, "UNAUTHORIZED", "bad access token")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := strings.TrimPrefix(r.URL.Path, "/psa/pins/")
+	switch {
+	case r.URL.Path == "/psa/pins" && r.Method == http.MethodGet:
+		s.lastLs = r.URL.Query()
+		list := PinList{Results: []PinStatus{}}
+		for _, st := range s.pins {
+			if name := s.lastLs.Get("name"); name == "" || st.Pin.Name == name {
+				list.Results = append(list.Results, st)
+			}
+		}
+		list.Count = len(list.Results)
+		json.NewEncoder(w).Encode(list)
+	case r.URL.Path == "/psa/pins" && r.Method == http.MethodPost:
+		s.next++
+		s.store(w, r, fmt.Sprintf("req-%d", s.next))
+	case r.Method == http.MethodPost:
+		if _, ok := s.pins[id]; !ok {
+			s.fail(w, http.StatusNotFound, "NOT_FOUND", "")
+			return
+		}
+		delete(s.pins, id)
+		s.next++
+		s.store(w, r, fmt.Sprintf("req-%d", s.next))
+	case r.Method == http.MethodGet:
+		st, ok := s.pins[id]
+		if !ok {
+			s.fail(w, http.StatusNotFound, "NOT_FOUND", "")
+			return
+		}
+		json.NewEncoder(w).Encode(st)
+	case r.Method == http.MethodDelete:
+		if _, ok := s.pins[id]; !ok {
+			s.fail(w, http.StatusNotFound, "NOT_FOUND", "")
+			return
+		}
+		delete(s.pins, id)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (s *fakeService) store(w http.ResponseWriter, r *http.Request, id string) {
+	var pin Pin
+	if err := json.NewDecoder(r.Body).Decode(&pin); err != nil || pin.Cid == "" {
+		s.fail(w, http.StatusBadRequest, "BAD_REQUEST", "missing cid")
+		return
+	}
+	st := PinStatus{
+		RequestID: id,
+		Status:    StatusPinned,
+		Created:   s.created,
+		Pin:       pin,
+		Delegates: []string{"/dnsaddr/pin.example.com"},
+	}
+	s.pins[id] = st
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(st)
+}
+
+func testCid(t *testing.T, s string) cid.Cid {
+	t.Helper()
+	c, err := cid.Decode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestPinLifecycle(t *testing.T) {
+	c, _ := newFakeService(t)
+	ctx := context.Background()
+	root := testCid(t, "QmWfVY9y3xjsixTgbd9AorQxH7VtMpzfx2HaWtsoUYecaX")
+
+	st, err := c.Add(ctx, root, Add.Name("hello"), Add.Origins("/ip4/127.0.0.1/tcp/4001/p2p/QmPeer"), Add.Meta(map[string]string{"app": "test"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Pin{
+		Cid:     root.String(),
+		Name:    "hello",
+		Origins: []string{"/ip4/127.0.0.1/tcp/4001/p2p/QmPeer"},
+		Meta:    map[string]string{"app": "test"},
+	}
+	if st.Status != StatusPinned || !reflect.DeepEqual(st.Pin, want) {
+		t.Fatalf("status = %+v, want %+v pinned", st, want)
+	}
+
+	got, err := c.Get(ctx, st.RequestID)
+	if err != nil || got.RequestID != st.RequestID {
+		t.Fatalf("get = %+v, %v", got, err)
+	}
+
+	next := testCid(t, "bafkreibm6jg3ux5qumhcn2b3flc3tyu6dmlb4xa7u5bf44yegnrjhc4yeq")
+	replaced, err := c.Replace(ctx, st.RequestID, next, Add.Name("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced.Pin.Cid != next.String() || replaced.RequestID == st.RequestID {
+		t.Errorf("replace = %+v", replaced)
+	}
+
+	if err := c.Delete(ctx, replaced.RequestID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Get(ctx, replaced.RequestID)
+	var svcErr *Error
+	if !errors.As(err, &svcErr) || svcErr.StatusCode != http.StatusNotFound || svcErr.Reason != "NOT_FOUND" {
+		t.Errorf("get deleted pin = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestLsQuery(t *testing.T) {
+	c, svc := newFakeService(t)
+	ctx := context.Background()
+	for _, name := range []string{"a", "b"} {
+		if _, err := c.Add(ctx, testCid(t, "QmWfVY9y3xjsixTgbd9AorQxH7VtMpzfx2HaWtsoUYecaX"), Add.Name(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := time.Date(2025, 1, 1, 0, 0, 0, 0, time.FixedZone("CET", 3600))
+	list, err := c.Ls(ctx,
+		Ls.Name("a", "exact"),
+		Ls.Status(StatusPinned, StatusQueued),
+		Ls.Before(before),
+		Ls.Limit(10),
+		Ls.Meta(map[string]string{"app": "test"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Count != 1 || len(list.Results) != 1 || list.Results[0].Pin.Name != "a" {
+		t.Errorf("ls = %+v, want the pin named a", list)
+	}
+
+	want := url.Values{
+		"name":   {"a"},
+		"match":  {"exact"},
+		"status": {"pinned,queued"},
+		"before": {"2024-12-31T23:00:00Z"},
+		"limit":  {"10"},
+		"meta":   {`{"app":"test"}`},
+	}
+	if !reflect.DeepEqual(svc.lastLs, want) {
+		t.Errorf("query = %v, want %v", svc.lastLs, want)
+	}
+
+	// Only pinned pins are listed by default.
+	if _, err := c.Ls(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := svc.lastLs.Get("status"); got != "pinned" {
+		t.Errorf("default status filter = %q, want pinned", got)
+	}
+}
+
+func TestLsLimit(t *testing.T) {
+	c, _ := newFakeService(t)
+	for _, limit := range []int{0, 1001} {
+		if _, err := c.Ls(context.Background(), Ls.Limit(limit)); err == nil {
+			t.Errorf("limit %d accepted", limit)
+		}
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	c, _ := newFakeService(t)
+	c.token = "wrong"
+
+	_, err := c.Ls(context.Background())
+	var svcErr *Error
+	if !errors.As(err, &svcErr) || svcErr.StatusCode != http.StatusUnauthorized || svcErr.Details != "bad access token" {
+		t.Fatalf("ls = %v, want the service's UNAUTHORIZED error", err)
+	}
+}
+
+func TestErrorWithoutBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream down", http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	_, err := NewClient(ts.URL, "").Get(context.Background(), "req-1")
+	var svcErr *Error
+	if !errors.As(err, &svcErr) || svcErr.StatusCode != http.StatusBadGateway || svcErr.Reason != "502 Bad Gateway" {
+		t.Fatalf("get = %v, want a 502 Error", err)
+	}
+}
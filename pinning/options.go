@@ -0,0 +1,159 @@
+package pinning
+
+import (
+	"errors"
+	"time"
+)
+
+type AddSettings struct {
+	Name    string
+	Origins []string
+	Meta    map[string]string
+}
+
+// AddOption configures the pin sent by Client.Add and Client.Replace.
+type AddOption func(*AddSettings) error
+
+func AddOptions(opts ...AddOption) (*AddSettings, error) {
+	options := &AddSettings{
+		Name:    "",
+		Origins: nil,
+		Meta:    nil,
+	}
+
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type addOpts struct{}
+
+var Add addOpts
+
+// Name names the pin.
+func (addOpts) Name(name string) AddOption {
+	return func(opts *AddSettings) error {
+		opts.Name = name
+		return nil
+	}
+}
+
+// Origins lists multiaddrs of peers known to provide the data, such as the
+// node it was added to.
+func (addOpts) Origins(origins ...string) AddOption {
+	return func(opts *AddSettings) error {
+		opts.Origins = append(opts.Origins, origins...)
+		return nil
+	}
+}
+
+// Meta attaches service specific metadata to the pin.
+func (addOpts) Meta(meta map[string]string) AddOption {
+	return func(opts *AddSettings) error {
+		opts.Meta = meta
+		return nil
+	}
+}
+
+type LsSettings struct {
+	Cids   []string
+	Name   string
+	Match  string
+	Status []Status
+	Before time.Time
+	After  time.Time
+	Limit  int
+	Meta   map[string]string
+}
+
+type LsOption func(*LsSettings) error
+
+func LsOptions(opts ...LsOption) (*LsSettings, error) {
+	options := &LsSettings{
+		Cids:   nil,
+		Name:   "",
+		Match:  "",
+		Status: []Status{StatusPinned},
+		Limit:  0,
+		Meta:   nil,
+	}
+
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+type lsOpts struct{}
+
+var Ls lsOpts
+
+// Cids only returns pins for the given CIDs.
+func (lsOpts) Cids(cids ...string) LsOption {
+	return func(opts *LsSettings) error {
+		opts.Cids = append(opts.Cids, cids...)
+		return nil
+	}
+}
+
+// Name only returns pins with a matching name. match is one of "exact",
+// "iexact", "partial" or "ipartial"; empty uses the service default.
+func (lsOpts) Name(name, match string) LsOption {
+	return func(opts *LsSettings) error {
+		opts.Name = name
+		opts.Match = match
+		return nil
+	}
+}
+
+// Status only returns pins in one of the given states. Only pinned pins are
+// returned by default.
+func (lsOpts) Status(status ...Status) LsOption {
+	return func(opts *LsSettings) error {
+		opts.Status = status
+		return nil
+	}
+}
+
+// Before only returns pins created before t.
+func (lsOpts) Before(t time.Time) LsOption {
+	return func(opts *LsSettings) error {
+		opts.Before = t
+		return nil
+	}
+}
+
+// After only returns pins created after t.
+func (lsOpts) After(t time.Time) LsOption {
+	return func(opts *LsSettings) error {
+		opts.After = t
+		return nil
+	}
+}
+
+// Limit caps the number of results of a single request; the spec allows 1 to
+// 1000.
+func (lsOpts) Limit(limit int) LsOption {
+	return func(opts *LsSettings) error {
+		if limit < 1 || limit > 1000 {
+			return errors.New("limit must be between 1 and 1000")
+		}
+		opts.Limit = limit
+		return nil
+	}
+}
+
+// Meta only returns pins whose metadata contains meta.
+func (lsOpts) Meta(meta map[string]string) LsOption {
+	return func(opts *LsSettings) error {
+		opts.Meta = meta
+		return nil
+	}
+}
@@ -9,4 +9,5 @@ var (
 	ErrNotSupported  = errors.New("operation not supported")
 	ErrNotReceiveRet = errors.New("no results received from ipfs peer")
 	ErrBadResponse   = errors.New("bad response from server")
+	ErrNoNodes       = errors.New("no ipfs node available")
 )